@@ -2,32 +2,37 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"log"
-	"net/http"
-	"net/url"
+	"log/slog"
 	"os"
 
+	"01-json-output/pkg/structured"
+
 	"github.com/ollama/ollama/api"
 )
 
-var (
-	FALSE = false
-	TRUE  = true
-)
+// logger writes the logs of the example and of the client on stderr, the
+// answer going to stdout.
+var logger = slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+// fatal logs err and exits.
+func fatal(err error) {
+	logger.Error("😡", "error", err)
+	os.Exit(1)
+}
 
 func main() {
 	ctx := context.Background()
 
-	var ollamaRawUrl string
-	if ollamaRawUrl = os.Getenv("OLLAMA_HOST"); ollamaRawUrl == "" {
-		ollamaRawUrl = "http://localhost:11434"
+	config, err := structured.LoadConfigFromEnv()
+	if err != nil {
+		fatal(err)
 	}
-
-	url, _ := url.Parse(ollamaRawUrl)
-
-	client := api.NewClient(url, http.DefaultClient)
+	client, err := config.NewClient()
+	if err != nil {
+		fatal(err)
+	}
+	client.Logger = logger
 
 	systemInstructions := `You are a helpful AI assistant. The user will enter the name of an animal.
 	The assistant will then return the following information about the animal:
@@ -48,25 +53,10 @@ func main() {
 		{Role: "user", Content: userContent},
 	}
 
-	req := &api.ChatRequest{
-		Model:    "granite3-moe:1b",
-		Messages: messages,
-		Options: map[string]interface{}{
-			"temperature":    0.0,
-			"repeat_last_n":  2,
-		},
-		Stream: &FALSE,
-		Format: json.RawMessage(`"json"`),
-	}
-
-	answer := ""
-	err := client.Chat(ctx, req, func(resp api.ChatResponse) error {
-		answer = resp.Message.Content
-		return nil
-	})
-
+	// no schema: the model is only asked to answer with JSON
+	answer, err := client.JSONChat(ctx, config.Model, messages, nil)
 	if err != nil {
-		log.Fatalln("😡", err)
+		fatal(err)
 	}
 	fmt.Println(answer)
 	fmt.Println()
@@ -4,59 +4,51 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
-	"net/http"
-	"net/url"
+	"log/slog"
 	"os"
 
+	"01-json-output/pkg/structured"
+
 	"github.com/ollama/ollama/api"
 )
 
-var (
-	FALSE = false
-	TRUE  = true
-)
+type Animal struct {
+	ScientificName  string   `json:"scientific_name"`
+	MainSpecies     string   `json:"main_species"`
+	AverageLength   float64  `json:"average_length" jsonschema:"minimum=0"`
+	AverageLifespan float64  `json:"average_lifespan" jsonschema:"minimum=0"`
+	AverageWeight   float64  `json:"average_weight" jsonschema:"minimum=0"`
+	Countries       []string `json:"countries"`
+}
+
+// logger writes the logs of the example and of the client on stderr, the
+// answer going to stdout.
+var logger = slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+// fatal logs err and exits.
+func fatal(err error) {
+	logger.Error("😡", "error", err)
+	os.Exit(1)
+}
 
 func main() {
 	ctx := context.Background()
 
-	var ollamaRawUrl string
-	if ollamaRawUrl = os.Getenv("OLLAMA_HOST"); ollamaRawUrl == "" {
-		ollamaRawUrl = "http://localhost:11434"
+	config, err := structured.LoadConfigFromEnv()
+	if err != nil {
+		fatal(err)
 	}
+	client, err := config.NewClient()
+	if err != nil {
+		fatal(err)
+	}
+	client.Logger = logger
 
-	url, _ := url.Parse(ollamaRawUrl)
-
-	client := api.NewClient(url, http.DefaultClient)
-
-	// define schema for a structured output
+	// define schema for a structured output from the Animal struct
 	// ref: https://ollama.com/blog/structured-outputs
-	schema := map[string]any{
-		"type": "object",
-		"properties": map[string]any{
-			"scientific_name": map[string]any{
-				"type": "string",
-			},
-			"main_species": map[string]any{
-				"type": "string",
-			},
-			"average_length": map[string]any{
-				"type": "number",
-			},
-			"average_lifespan": map[string]any{
-				"type": "number",
-			},
-			"average_weight": map[string]any{
-				"type": "number",
-			},
-			"countries": map[string]any{
-				"type": "array",
-				"items": map[string]any{
-					"type": "string",
-				},
-			},
-		},
-		"required": []string{"scientific_name", "main_species", "average_length", "average_lifespan", "average_weight", "countries"},
+	schema, err := structured.SchemaOf[Animal]()
+	if err != nil {
+		fatal(err)
 	}
 
 	// convert schema to json with pretty print
@@ -64,7 +56,7 @@ func main() {
 	// jsonModel, err := json.Marshal(schema)
 	fmt.Println(string(jsonModel))
 	if err != nil {
-		log.Fatalln("😡", err)
+		fatal(err)
 	}
 
 	data := `Information about the chicken:
@@ -84,25 +76,9 @@ func main() {
 		{Role: "user", Content: userContent},
 	}
 
-	req := &api.ChatRequest{
-		Model:    "granite3-moe:1b",
-		Messages: messages,
-		Options: map[string]interface{}{
-			"temperature":    0.0,
-			"repeat_last_n":  2,
-		},
-		Stream: &FALSE,
-		Format: json.RawMessage(jsonModel),
-	}
-
-	answer := ""
-	err = client.Chat(ctx, req, func(resp api.ChatResponse) error {
-		answer = resp.Message.Content
-		return nil
-	})
-
+	answer, err := client.JSONChat(ctx, config.Model, messages, schema)
 	if err != nil {
-		log.Fatalln("😡", err)
+		fatal(err)
 	}
 	fmt.Println(answer)
 	fmt.Println()
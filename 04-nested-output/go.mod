@@ -0,0 +1,14 @@
+module 04-nested-output
+
+go 1.23.1
+
+require github.com/ollama/ollama v0.5.1
+
+require 01-json-output v0.0.0
+
+require (
+	github.com/BurntSushi/toml v1.4.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace 01-json-output => ../
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"01-json-output/pkg/structured"
+
+	"github.com/ollama/ollama/api"
+)
+
+type Country struct {
+	Name       string `json:"name"`
+	ISOCode    string `json:"iso_code" jsonschema:"description=ISO 3166-1 alpha-2 code,pattern=^[A-Z]{2}$"`
+	Population int    `json:"population" jsonschema:"minimum=0"`
+}
+
+type Size struct {
+	AverageLength float64 `json:"average_length" jsonschema:"minimum=0"`
+	AverageWeight float64 `json:"average_weight" jsonschema:"minimum=0"`
+}
+
+type Animal struct {
+	ScientificName  string    `json:"scientific_name"`
+	MainSpecies     string    `json:"main_species"`
+	Size            Size      `json:"size"`
+	AverageLifespan float64   `json:"average_lifespan" jsonschema:"minimum=0"`
+	Countries       []Country `json:"countries"`
+}
+
+// logger writes the logs of the example and of the client on stderr, the
+// answer going to stdout.
+var logger = slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+// fatal logs err and exits.
+func fatal(err error) {
+	logger.Error("😡", "error", err)
+	os.Exit(1)
+}
+
+func main() {
+	ctx := context.Background()
+
+	config, err := structured.LoadConfigFromEnv()
+	if err != nil {
+		fatal(err)
+	}
+	client, err := config.NewClient()
+	if err != nil {
+		fatal(err)
+	}
+	client.Logger = logger
+	client.MaxRetries = 2
+	client.Repair = true
+
+	data := `Information about the chicken:
+	- scientific_name: Gallus gallus
+	- main_species: Poultry
+	- average_length: 1.5 to 1.75 meters
+	- average_weight: 5 to 7 kilograms
+	- average_lifespan: 10 to 20 years
+	- countries:
+	  - China (CN), population 1.4 billion
+	  - India (IN), population 1.4 billion
+	  - Egypt (EG), population 110 million
+	`
+
+	userContent := "Tell me about chicken"
+
+	// Prompt construction
+	messages := []api.Message{
+		{Role: "system", Content: data},
+		{Role: "user", Content: userContent},
+	}
+
+	// the schema is generated from Animal, with a nested object (size)
+	// and an array of objects (countries)
+	extraction, err := structured.ExtractResult[Animal](ctx, client, config.Model, messages)
+	if err != nil {
+		fatal(err)
+	}
+	animal := extraction.Data
+
+	answer, err := json.MarshalIndent(animal, "", "  ")
+	if err != nil {
+		fatal(err)
+	}
+	fmt.Println(string(answer))
+	fmt.Println()
+
+	for _, country := range animal.Countries {
+		fmt.Printf("%s (%s): %d\n", country.Name, country.ISOCode, country.Population)
+	}
+
+	metrics := extraction.Metrics
+	logger.Info("metrics",
+		"requests", metrics.Requests,
+		"prompt_tokens", metrics.PromptTokens,
+		"output_tokens", metrics.OutputTokens,
+		"tokens_per_second", fmt.Sprintf("%.1f", metrics.TokensPerSecond()),
+		"wall_time", metrics.WallTime,
+	)
+}
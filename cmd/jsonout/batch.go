@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"time"
+
+	"01-json-output/pkg/batch"
+)
+
+// batchFiles are the files and the partition of a batch, see the flags.
+type batchFiles struct {
+	input, column, out string
+	checkpoint         string
+	resume             bool
+	peers, peer        string
+	peerCheckpoints    string
+}
+
+// runBatch extracts the records of the input file and writes the results
+// to out (stdout when empty). The records done are saved in the checkpoint
+// file, and skipped with resume. With peers, only the share of peer is
+// extracted; on resume, the records found in peerCheckpoints (the
+// checkpoints of the other instances, including the ones that left) are
+// skipped too, so the records changing hands with the ring are not
+// extracted twice.
+func runBatch(ctx context.Context, b *batch.Batch, files batchFiles) {
+	records, err := batch.ReadFile(files.input, files.column)
+	if err != nil {
+		fatal(err)
+	}
+	if files.peers != "" {
+		peers := strings.Split(files.peers, ",")
+		if !slices.Contains(peers, files.peer) {
+			fatal("--peer must be one of --peers")
+		}
+		ring, err := batch.NewRing(peers, 0)
+		if err != nil {
+			fatal(err)
+		}
+		records = ring.Partition(records, files.peer)
+	}
+
+	out, checkpoint, resume := files.out, files.checkpoint, files.resume
+
+	if checkpoint == "" && out != "" {
+		checkpoint = out + ".checkpoint"
+	}
+	if resume && checkpoint == "" {
+		fatal("--resume needs --out or --checkpoint")
+	}
+	if checkpoint != "" {
+		if !resume {
+			// a new batch
+			if err := os.Remove(checkpoint); err != nil && !errors.Is(err, os.ErrNotExist) {
+				fatal(err)
+			}
+		}
+		if b.Checkpoint, err = batch.OpenCheckpoint(checkpoint); err != nil {
+			fatal(err)
+		}
+		defer b.Checkpoint.Close()
+	}
+	if files.peerCheckpoints != "" {
+		if !resume {
+			fatal("--peer-checkpoints needs --resume")
+		}
+		for _, path := range strings.Split(files.peerCheckpoints, ",") {
+			if path == checkpoint {
+				continue
+			}
+			peer, err := batch.ReadCheckpoint(path)
+			if err != nil {
+				fatal(err)
+			}
+			b.Peers = append(b.Peers, peer)
+		}
+	}
+
+	w := os.Stdout
+	if out != "" {
+		if w, err = openOutput(out, resume); err != nil {
+			fatal(err)
+		}
+		defer w.Close()
+	}
+
+	// stop cleanly on Ctrl+C, the checkpoint holding the records done
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	stats, err := b.Run(ctx, records, w)
+	logger.Info("batch done",
+		"records", stats.Total,
+		"ok", stats.OK,
+		"failed", stats.Failed,
+		"skipped", stats.Skipped,
+		"retry", stats.Retry,
+		"elapsed", stats.Elapsed.Round(time.Millisecond),
+		"records_per_second", fmt.Sprintf("%.2f", stats.Throughput()),
+		"mean_latency", stats.MeanLatency().Round(time.Millisecond),
+	)
+	if err != nil {
+		msg := "batch interrupted"
+		if checkpoint != "" {
+			msg += ", run again with --resume to continue"
+		}
+		logger.Error("😡 "+msg, "error", err)
+		os.Exit(1)
+	}
+	if stats.Retry > 0 {
+		logger.Warn("records failed with a chat error, run again with --resume to retry them", "records", stats.Retry)
+	}
+}
+
+// openOutput opens the output file of a batch: a new batch starts from an
+// empty file, like its checkpoint, while resume appends to the results
+// already there.
+func openOutput(out string, resume bool) (*os.File, error) {
+	flag := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if resume {
+		flag = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	return os.OpenFile(out, flag, 0o644)
+}
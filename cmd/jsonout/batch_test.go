@@ -0,0 +1,36 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenOutput(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "animals.jsonl")
+	if err := os.WriteFile(out, []byte("{\"line\":1}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	write := func(resume bool) string {
+		t.Helper()
+		w, err := openOutput(out, resume)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.WriteString("{\"line\":2}\n")
+		w.Close()
+		data, err := os.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	// a new batch doesn't keep the results of the previous one
+	if got := write(false); got != "{\"line\":2}\n" {
+		t.Errorf("new batch: out = %q", got)
+	}
+	if got := write(true); got != "{\"line\":2}\n{\"line\":2}\n" {
+		t.Errorf("resume: out = %q", got)
+	}
+}
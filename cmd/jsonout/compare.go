@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"01-json-output/pkg/structured"
+
+	"github.com/ollama/ollama/api"
+)
+
+// maxCell is the width above which the values of the comparison are
+// truncated.
+const maxCell = 40
+
+// compare sends the request to the models and prints their answers side
+// by side, the fields with different values being marked with "≠".
+func compare(ctx context.Context, client *structured.Client, models []string, messages []api.Message, schema any, w io.Writer) {
+	comparison := client.Compare(ctx, models, messages, schema)
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	row := func(name string, cell func(structured.ModelAnswer) string) {
+		cells := []string{name}
+		for _, answer := range comparison.Answers {
+			cells = append(cells, cell(answer))
+		}
+		fmt.Fprintln(table, strings.Join(cells, "\t"))
+	}
+
+	row("", func(answer structured.ModelAnswer) string { return answer.Model })
+	for _, field := range comparison.Fields {
+		name := field
+		if slices.Contains(comparison.Differences, field) {
+			name = "≠ " + field
+		}
+		row(name, func(answer structured.ModelAnswer) string {
+			if answer.Err != nil {
+				return "-"
+			}
+			value, ok := answer.Values[field]
+			if !ok {
+				return "(missing)"
+			}
+			data, _ := json.Marshal(value)
+			return truncate(string(data))
+		})
+	}
+	row("latency", func(answer structured.ModelAnswer) string {
+		return answer.Metrics.WallTime.Round(time.Millisecond).String()
+	})
+	row("prompt tokens", func(answer structured.ModelAnswer) string { return fmt.Sprint(answer.Metrics.PromptTokens) })
+	row("output tokens", func(answer structured.ModelAnswer) string { return fmt.Sprint(answer.Metrics.OutputTokens) })
+	row("tokens/s", func(answer structured.ModelAnswer) string {
+		return fmt.Sprintf("%.1f", answer.Metrics.TokensPerSecond())
+	})
+	row("error", func(answer structured.ModelAnswer) string {
+		if answer.Err == nil {
+			return ""
+		}
+		return truncate(answer.Err.Error())
+	})
+	table.Flush()
+}
+
+func truncate(s string) string {
+	if len([]rune(s)) <= maxCell {
+		return s
+	}
+	return string([]rune(s)[:maxCell-1]) + "…"
+}
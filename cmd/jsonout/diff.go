@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"01-json-output/pkg/structured"
+)
+
+// diff runs the diff command, which lists the changes between two versions
+// of a schema file and fails on breaking changes, unless --force is set:
+//
+//	jsonout diff animal.schema.json animal.v2.schema.json
+func diff(args []string) {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	force := flags.Bool("force", false, "don't fail on breaking changes")
+	verbose := flags.Bool("verbose", false, "log at debug level")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: jsonout diff [--force] previous.schema.json edited.schema.json")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	setVerbose(*verbose)
+	if flags.NArg() != 2 {
+		flags.Usage()
+		fatal("two schema files are expected")
+	}
+
+	from, err := structured.LoadSchema(flags.Arg(0))
+	if err != nil {
+		fatal(err)
+	}
+	to, err := structured.LoadSchema(flags.Arg(1))
+	if err != nil {
+		fatal(err)
+	}
+	changes, err := structured.DiffSchemas(from, to)
+	if err != nil {
+		fatal(err)
+	}
+	for _, change := range changes {
+		fmt.Println(change)
+	}
+
+	breaking := structured.BreakingChanges(changes)
+	if len(breaking) > 0 && !*force {
+		fatal(fmt.Sprintf("%d breaking change(s), bump the schema version or use --force", len(breaking)))
+	}
+}
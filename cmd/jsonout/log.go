@@ -0,0 +1,24 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+)
+
+// logger writes the logs of jsonout on stderr, at info level unless
+// --verbose is set.
+var logger = slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+// setVerbose logs at debug level, including the raw requests and responses
+// of the structured client.
+func setVerbose(verbose bool) {
+	if verbose {
+		logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	}
+}
+
+// fatal logs err and exits.
+func fatal(err any) {
+	logger.Error("😡", "error", err)
+	os.Exit(1)
+}
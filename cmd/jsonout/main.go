@@ -0,0 +1,249 @@
+// Command jsonout asks an Ollama model for a JSON answer, optionally
+// constrained by a JSON schema file.
+//
+//	jsonout --model granite3-moe:1b --schema animal.schema.json --prompt "chicken" --out result.json
+//	echo "Tell me about chicken" | jsonout --schema animal.schema.json --pretty
+//
+// With --input, every line (or CSV column, or JSONL field) of a file is a
+// record, and a JSON line is appended to the output for each one:
+//
+//	jsonout --schema animal.schema.json --input animals.txt --template "Tell me about {input}" --out animals.jsonl
+//
+// With --compare, the prompt is sent to several models and their answers
+// are printed side by side:
+//
+//	jsonout --schema animal.schema.json --prompt "chicken" --compare granite3-moe:1b,llama3.2:3b,qwen2.5:7b
+//
+// The prune command removes the old results of a response cache (see
+// --cache):
+//
+//	jsonout prune --cache .jsonout-cache --max-age 168h
+//
+// The diff command lists the changes between two versions of a schema file,
+// and fails on breaking changes:
+//
+//	jsonout diff animal.schema.json animal.v2.schema.json
+//
+// The validate command checks JSON files against a schema file, e.g. answers
+// fixed by hand before loading them:
+//
+//	jsonout validate --schema animal.schema.json chicken.json
+//
+// The host, model, temperature, retries and timeout are read from the
+// configuration file (see --config), the flags set overriding it.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"01-json-output/pkg/batch"
+	"01-json-output/pkg/structured"
+
+	"github.com/ollama/ollama/api"
+)
+
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "prune":
+			prune(os.Args[2:])
+			return
+		case "diff":
+			diff(os.Args[2:])
+			return
+		case "validate":
+			validate(os.Args[2:])
+			return
+		}
+	}
+
+	configFile := flag.String("config", "", "configuration file (.yaml, .yml or .toml), STRUCTURED_CONFIG or ./config.yaml by default")
+	model := flag.String("model", structured.DefaultModel, "model to use, the one of the configuration by default")
+	schemaFile := flag.String("schema", "", "JSON schema file (.json, .yaml or .yml) of the answer, plain JSON mode when empty")
+	prompt := flag.String("prompt", "", `prompt, read from stdin when empty or "-"`)
+	system := flag.String("system", "", "optional system message (instructions or data to extract from)")
+	out := flag.String("out", "", "output file (stdout when empty), overwritten unless --resume")
+	pretty := flag.Bool("pretty", false, "indent the JSON output")
+	strategy := flag.String("strategy", "", "direct, prompted, two-pass, tool-fill or per-field")
+	retries := flag.Int("retries", 0, "new attempts when the answer doesn't match the schema")
+	fallbacks := flag.String("fallbacks", "", "comma-separated models tried in order when the answers still don't match the schema")
+	samples := flag.Int("samples", 0, "answers asked for and merged by majority vote per field (self-consistency)")
+	sampleTemperature := flag.Float64("sample-temperature", structured.DefaultVotingTemperature, "temperature of the voting samples")
+	repair := flag.Bool("repair", false, "send validation errors back to the model on retry")
+	strict := flag.Bool("strict", false, "don't clean the answer (fences, trailing commas...) before validation")
+	capabilities := flag.String("capabilities", "", "JSON file of model capabilities, merged into the defaults")
+	showMetrics := flag.Bool("metrics", false, "print the tokens and durations of the requests on stderr")
+	cache := flag.String("cache", "", "directory of the response cache, answers of identical requests are reused")
+	cacheTTL := flag.Duration("cache-ttl", 0, "time the cached answers are reused (forever when 0)")
+	compareModels := flag.String("compare", "", "comma-separated models to ask the same prompt, their answers are compared side by side")
+	input := flag.String("input", "", "batch mode: file of inputs, one per line (.txt), CSV column or JSONL field")
+	column := flag.String("column", "", "CSV column or JSONL field of the inputs (first column, or \"prompt\")")
+	template := flag.String("template", "", `batch mode: prompt template, "{input}" being replaced by each input`)
+	concurrency := flag.Int("concurrency", 1, "batch mode: records extracted at the same time")
+	rate := flag.Float64("rate", 0, "batch mode: maximum chat requests per second, retries and samples included (unlimited when 0)")
+	burst := flag.Int("burst", 1, "batch mode: requests sent at once within the rate")
+	checkpoint := flag.String("checkpoint", "", `batch mode: checkpoint file of the records done ("<out>.checkpoint" by default)`)
+	resume := flag.Bool("resume", false, "batch mode: skip the records of the checkpoint of an interrupted batch")
+	peers := flag.String("peers", "", "batch mode: comma-separated names of the instances sharing the input file")
+	peer := flag.String("peer", "", "batch mode: name of this instance in --peers, which extracts its share of the records")
+	peerCheckpoints := flag.String("peer-checkpoints", "", "batch mode: comma-separated checkpoints of the other instances, whose records are skipped on --resume")
+	verbose := flag.Bool("verbose", false, "log at debug level, with the raw requests and responses")
+	flag.Parse()
+	setVerbose(*verbose)
+
+	ctx := context.Background()
+
+	config, err := loadConfig(*configFile)
+	if err != nil {
+		fatal(err)
+	}
+	// the flags set on the command line override the configuration
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "model":
+			config.Model = *model
+		case "retries":
+			config.Retries = *retries
+		case "repair":
+			config.Repair = *repair
+		}
+	})
+	*model = config.Model
+
+	client, err := config.NewClient()
+	if err != nil {
+		fatal(err)
+	}
+	client.Logger = logger
+	if *samples > 1 {
+		client.Voting = &structured.Voting{Samples: *samples, Temperature: *sampleTemperature}
+	}
+	if *fallbacks != "" {
+		client.Fallbacks = strings.Split(*fallbacks, ",")
+	}
+	client.Strict = *strict
+	if *cache != "" {
+		client.Cache = &structured.FileCache{Dir: *cache, TTL: *cacheTTL}
+	}
+	if *capabilities != "" {
+		caps, err := structured.LoadCapabilities(*capabilities)
+		if err != nil {
+			fatal(err)
+		}
+		client.Capabilities = client.Capabilities.Merge(caps)
+	}
+	if *strategy != "" {
+		if client.Strategy, err = structured.StrategyByName(*strategy); err != nil {
+			fatal(err)
+		}
+	}
+
+	var schema any
+	if *schemaFile != "" {
+		if schema, err = structured.LoadSchema(*schemaFile); err != nil {
+			fatal(err)
+		}
+	}
+
+	if *input != "" {
+		runBatch(ctx, &batch.Batch{
+			Client:      client,
+			Model:       *model,
+			Schema:      schema,
+			System:      *system,
+			Template:    *template,
+			Concurrency: *concurrency,
+			Rate:        *rate,
+			Burst:       *burst,
+		}, batchFiles{
+			input:           *input,
+			column:          *column,
+			out:             *out,
+			checkpoint:      *checkpoint,
+			resume:          *resume,
+			peers:           *peers,
+			peer:            *peer,
+			peerCheckpoints: *peerCheckpoints,
+		})
+		return
+	}
+
+	userContent := *prompt
+	if userContent == "" || userContent == "-" {
+		input, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fatal(err)
+		}
+		userContent = strings.TrimSpace(string(input))
+	}
+
+	// Prompt construction
+	messages := []api.Message{}
+	if *system != "" {
+		messages = append(messages, api.Message{Role: "system", Content: *system})
+	}
+	messages = append(messages, api.Message{Role: "user", Content: userContent})
+
+	if *compareModels != "" {
+		compare(ctx, client, strings.Split(*compareModels, ","), messages, schema, os.Stdout)
+		return
+	}
+
+	result, err := client.Chat(ctx, *model, messages, schema)
+	if err != nil {
+		fatal(err)
+	}
+	if *showMetrics {
+		m := result.Metrics
+		logger.Info("metrics",
+			"requests", m.Requests,
+			"prompt_tokens", m.PromptTokens,
+			"prompt_tokens_per_second", fmt.Sprintf("%.1f", m.PromptTokensPerSecond()),
+			"output_tokens", m.OutputTokens,
+			"tokens_per_second", fmt.Sprintf("%.1f", m.TokensPerSecond()),
+			"truncated", m.Truncated,
+			"wall_time", m.WallTime.Round(time.Millisecond),
+			"cached", m.Cached,
+		)
+	}
+	for _, field := range slices.Sorted(maps.Keys(result.Confidence)) {
+		logger.Info("confidence", "field", field, "value", fmt.Sprintf("%.0f%%", 100*result.Confidence[field]))
+	}
+
+	answer := []byte(result.Raw)
+	if *pretty {
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, answer, "", "  "); err != nil {
+			fatal(err)
+		}
+		answer = indented.Bytes()
+	}
+	answer = append(answer, '\n')
+
+	if *out == "" {
+		os.Stdout.Write(answer)
+		return
+	}
+	if err := os.WriteFile(*out, answer, 0o644); err != nil {
+		fatal(err)
+	}
+}
+
+// loadConfig loads path, or the configuration found by
+// structured.LoadConfigFromEnv when path is empty.
+func loadConfig(path string) (structured.Config, error) {
+	if path == "" {
+		return structured.LoadConfigFromEnv()
+	}
+	return structured.LoadConfig(path)
+}
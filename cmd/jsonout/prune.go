@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+
+	"01-json-output/pkg/structured"
+)
+
+// prune runs the prune command, which removes the old results of a
+// response cache:
+//
+//	jsonout prune --cache .jsonout-cache --max-age 168h --max-bytes 10000000
+func prune(args []string) {
+	flags := flag.NewFlagSet("prune", flag.ExitOnError)
+	cache := flags.String("cache", "", "directory of the response cache")
+	maxAge := flags.Duration("max-age", 0, "remove the results older than this (e.g. 168h)")
+	maxEntries := flags.Int("max-entries", 0, "keep at most this many results, the newest ones")
+	maxBytes := flags.Int64("max-bytes", 0, "keep at most this many bytes of results, the newest ones")
+	verbose := flags.Bool("verbose", false, "log at debug level")
+	flags.Parse(args)
+	setVerbose(*verbose)
+
+	if *cache == "" {
+		fatal("--cache is required")
+	}
+	stats, err := (&structured.FileCache{Dir: *cache}).Prune(structured.Retention{
+		MaxAge:     *maxAge,
+		MaxEntries: *maxEntries,
+		MaxBytes:   *maxBytes,
+	})
+	logger.Info("cache pruned", "removed", stats.Removed, "removed_bytes", stats.Bytes, "kept", stats.Kept, "kept_bytes", stats.KeptBytes)
+	if err != nil {
+		fatal(err)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"01-json-output/pkg/structured"
+)
+
+// validate runs the validate command, which checks JSON files against a
+// schema file with the validator of the structured client, and fails when
+// one doesn't match. The files are checked as they are, unless --clean is
+// set: they then go through CleanJSON first, like the model answers.
+//
+//	jsonout validate --schema animal.schema.json chicken.json cow.json
+func validate(args []string) {
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	schemaFile := flags.String("schema", "", "JSON schema file (.json, .yaml or .yml)")
+	clean := flags.Bool("clean", false, "clean the files (fences, trailing commas...) before validation, like the model answers")
+	verbose := flags.Bool("verbose", false, "log at debug level")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: jsonout validate [--clean] --schema animal.schema.json file.json...")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	setVerbose(*verbose)
+	if *schemaFile == "" || flags.NArg() == 0 {
+		flags.Usage()
+		fatal("a schema and at least one JSON file are expected")
+	}
+
+	schema, err := structured.LoadSchema(*schemaFile)
+	if err != nil {
+		fatal(err)
+	}
+	invalid := 0
+	for _, path := range flags.Args() {
+		violations, cleaned, err := checkFile(schema, path, *clean)
+		if err != nil {
+			fatal(err)
+		}
+		if cleaned {
+			logger.Warn("file needed cleaning, it is not valid JSON as is", "file", path)
+		}
+		if len(violations) == 0 {
+			logger.Debug("valid", "file", path)
+			continue
+		}
+		invalid++
+		for _, violation := range violations {
+			fmt.Printf("%s: %s\n", path, violation)
+		}
+	}
+
+	if invalid > 0 {
+		fatal(fmt.Sprintf("%d of %d file(s) don't match the schema", invalid, flags.NArg()))
+	}
+}
+
+// checkFile validates the file at path against schema, after CleanJSON when
+// clean is set; cleaned tells whether the file was not valid JSON as is.
+func checkFile(schema any, path string, clean bool) (violations []structured.Violation, cleaned bool, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+	raw := string(data)
+	if clean && !json.Valid(data) {
+		raw, cleaned = structured.CleanJSON(raw), true
+	}
+	violations, err = structured.ValidateJSON(schema, raw)
+	return violations, cleaned, err
+}
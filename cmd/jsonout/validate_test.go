@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"01-json-output/pkg/structured"
+)
+
+func TestCheckFile(t *testing.T) {
+	schema, err := structured.LoadSchema("animal.schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := `{"scientific_name": "Gallus gallus", "main_species": "bird", "average_length": 0.5, "average_weight": 2, "average_lifespan": 8, "countries": ["France"]}`
+	files := map[string]struct {
+		content string
+		clean   bool
+		// valid and cleaned are the expected results
+		valid, cleaned bool
+	}{
+		"valid":            {valid, false, true, false},
+		"valid, clean":     {valid, true, true, false},
+		"truncated":        {`{"scientific_name": "Gallus gallus", "countries": ["France",`, false, false, false},
+		"truncated, clean": {`{"scientific_name": "Gallus gallus", "countries": ["France",`, true, false, true},
+		"fenced":           {"Here it is:\n```json\n" + valid[:len(valid)-1] + ",}\n```", false, false, false},
+		"fenced, clean":    {"Here it is:\n```json\n" + valid[:len(valid)-1] + ",}\n```", true, true, true},
+		"missing field":    {`{"scientific_name": "Gallus gallus"}`, false, false, false},
+	}
+	for name, f := range files {
+		path := filepath.Join(t.TempDir(), "animal.json")
+		if err := os.WriteFile(path, []byte(f.content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		violations, cleaned, err := checkFile(schema, path, f.clean)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if (len(violations) == 0) != f.valid || cleaned != f.cleaned {
+			t.Errorf("%s: violations = %v, cleaned = %v", name, violations, cleaned)
+		}
+	}
+}
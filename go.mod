@@ -1,3 +1,10 @@
 module 01-json-output
 
 go 1.23.1
+
+require (
+	github.com/ollama/ollama v0.5.1
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require github.com/BurntSushi/toml v1.4.0
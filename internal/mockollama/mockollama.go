@@ -0,0 +1,175 @@
+// Package mockollama emulates the Ollama chat API with an httptest server,
+// so the structured package can be tested without a running Ollama.
+//
+//	server := mockollama.New(mockollama.Response{Content: `{"name":"chicken"}`})
+//	defer server.Close()
+//	client, _ := structured.NewClient(server.URL)
+package mockollama
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/ollama/ollama/api"
+)
+
+// DefaultVersion is the version reported by /api/version.
+const DefaultVersion = "0.5.1"
+
+// Response is the answer of the server to one /api/chat request.
+type Response struct {
+	// Content of the assistant message. Streamed requests receive it in
+	// Chunks when set, one chunk per character otherwise.
+	Content string
+	Chunks  []string
+
+	ToolCalls []api.ToolCall
+
+	DoneReason      string
+	PromptEvalCount int
+	EvalCount       int
+
+	// Status, when set, makes the request fail with this HTTP status and
+	// the Error message.
+	Status int
+	Error  string
+}
+
+// Server is a fake Ollama server answering the queued responses in order.
+// The last response is repeated once the queue is exhausted.
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	version   string
+	responses []Response
+	last      *Response
+	byModel   map[string][]Response
+	requests  []api.ChatRequest
+}
+
+// New starts a server answering responses.
+func New(responses ...Response) *Server {
+	s := &Server{version: DefaultVersion, responses: responses}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/version", s.handleVersion)
+	mux.HandleFunc("POST /api/chat", s.handleChat)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// Push queues more responses.
+func (s *Server) Push(responses ...Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses = append(s.responses, responses...)
+}
+
+// PushModel queues responses for the requests of model only. They are
+// answered before the responses of the common queue.
+func (s *Server) PushModel(model string, responses ...Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.byModel == nil {
+		s.byModel = map[string][]Response{}
+	}
+	s.byModel[model] = append(s.byModel[model], responses...)
+}
+
+// SetVersion changes the version reported by /api/version.
+func (s *Server) SetVersion(version string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.version = version
+}
+
+// Requests returns the chat requests received so far.
+func (s *Server) Requests() []api.ChatRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]api.ChatRequest{}, s.requests...)
+}
+
+func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	version := s.version
+	s.mu.Unlock()
+	json.NewEncoder(w).Encode(map[string]string{"version": version})
+}
+
+func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
+	var req api.ChatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	var resp Response
+	switch {
+	case len(s.byModel[req.Model]) > 0:
+		resp = s.byModel[req.Model][0]
+		s.byModel[req.Model] = s.byModel[req.Model][1:]
+	case len(s.responses) > 0:
+		resp = s.responses[0]
+		s.responses = s.responses[1:]
+		s.last = &resp
+	case s.last != nil:
+		resp = *s.last
+	default:
+		s.mu.Unlock()
+		writeError(w, http.StatusInternalServerError, "mockollama: no response queued")
+		return
+	}
+	s.mu.Unlock()
+
+	if resp.Status != 0 {
+		writeError(w, resp.Status, resp.Error)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	encoder := json.NewEncoder(w)
+
+	if req.Stream == nil || *req.Stream {
+		chunks := resp.Chunks
+		if chunks == nil {
+			for _, ch := range resp.Content {
+				chunks = append(chunks, string(ch))
+			}
+		}
+		for _, chunk := range chunks {
+			encoder.Encode(api.ChatResponse{
+				Model:   req.Model,
+				Message: api.Message{Role: "assistant", Content: chunk},
+			})
+		}
+		resp.Content = ""
+	}
+
+	final := api.ChatResponse{
+		Model:      req.Model,
+		Message:    api.Message{Role: "assistant", Content: resp.Content, ToolCalls: resp.ToolCalls},
+		Done:       true,
+		DoneReason: resp.DoneReason,
+	}
+	if final.DoneReason == "" {
+		final.DoneReason = "stop"
+	}
+	final.PromptEvalCount = resp.PromptEvalCount
+	final.EvalCount = resp.EvalCount
+	encoder.Encode(final)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if message == "" {
+		w.Write([]byte("{}\n"))
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,254 @@
+// Package batch runs a structured extraction for each record of an input
+// file and writes one JSON line per record:
+//
+//	records, err := batch.ReadFile("animals.txt", "")
+//	b := &batch.Batch{Client: client, Model: "granite3-moe:1b", Schema: schema, Template: "Tell me about {input}"}
+//	stats, err := b.Run(ctx, records, out)
+//
+// A record that fails (chat error, answer not matching the schema) is
+// written with its error, and the batch goes on. Records are extracted by
+// Concurrency workers, their chat requests optionally rate limited, and
+// written as they complete.
+package batch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"01-json-output/pkg/structured"
+
+	"github.com/ollama/ollama/api"
+)
+
+// Batch holds the settings of the extraction.
+type Batch struct {
+	Client *structured.Client
+	Model  string
+	// Schema of the answers, plain JSON mode when nil.
+	Schema any
+	// System is an optional system message sent with every record.
+	System string
+	// Template builds the prompt, "{input}" being replaced by the input of
+	// the record. The input is the prompt when Template is empty.
+	Template string
+
+	// Concurrency is the number of records extracted at the same time,
+	// 1 when not set.
+	Concurrency int
+	// Rate limits the chat requests per second, unlimited when 0: each
+	// request of a record counts (retries, voting samples, strategy
+	// passes...). Burst requests can be sent at once (1 when not set).
+	Rate  float64
+	Burst int
+
+	// Checkpoint, when set, skips the records it holds and records the
+	// ones done. Records failing with a chat error (not an invalid answer)
+	// are neither recorded nor written, to be extracted again on resume
+	// without duplicating their line in the output (see Stats.Retry).
+	Checkpoint *Checkpoint
+	// Peers are the checkpoints of the other instances sharing the input
+	// (see Ring), read with ReadCheckpoint: their records are skipped too,
+	// so after a membership change the records a peer already did are
+	// not extracted again by their new owner.
+	Peers []*Checkpoint
+}
+
+// Output is the JSON line written for a record.
+type Output struct {
+	Line       int                `json:"line"`
+	Input      string             `json:"input"`
+	Result     json.RawMessage    `json:"result,omitempty"`
+	Model      string             `json:"model,omitempty"`
+	Confidence map[string]float64 `json:"confidence,omitempty"`
+	Error      string             `json:"error,omitempty"`
+	Violations []string           `json:"violations,omitempty"`
+}
+
+// Stats counts the records of a batch.
+type Stats struct {
+	Total  int
+	OK     int
+	Failed int
+	// Skipped counts the records already in the checkpoint, or in the
+	// ones of the peers.
+	Skipped int
+	// Retry counts the records left for the next run with a checkpoint:
+	// they failed with a chat error and were not written.
+	Retry int
+	// Elapsed is the duration of the batch, Latency the sum of the
+	// extraction durations of the records.
+	Elapsed time.Duration
+	Latency time.Duration
+}
+
+// Throughput returns the records per second.
+func (s Stats) Throughput() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Total) / s.Elapsed.Seconds()
+}
+
+// MeanLatency returns the mean extraction duration of a record.
+func (s Stats) MeanLatency() time.Duration {
+	if s.Total == 0 {
+		return 0
+	}
+	return s.Latency / time.Duration(s.Total)
+}
+
+// extraction is an output with its extraction duration.
+type extraction struct {
+	record  Record
+	output  Output
+	latency time.Duration
+	// retryable is set for chat errors, the record being worth another try
+	retryable bool
+}
+
+// Run extracts the records and writes the outputs to w as JSON lines, in
+// the order they complete. Record failures are reported in the outputs,
+// except the retried ones of a checkpointed batch; only write errors and
+// the cancellation of ctx stop the batch.
+func (b *Batch) Run(ctx context.Context, records []Record, w io.Writer) (Stats, error) {
+	start := time.Now()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if b.Rate > 0 {
+		ctx = structured.WithRateLimiter(ctx, structured.NewRateLimiter(b.Rate, b.Burst))
+	}
+
+	var stats Stats
+	var todo []Record
+	for _, record := range records {
+		if b.done(record) {
+			stats.Skipped++
+		} else {
+			todo = append(todo, record)
+		}
+	}
+
+	jobs := make(chan Record)
+	go func() {
+		defer close(jobs)
+		for _, record := range todo {
+			select {
+			case jobs <- record:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	results := make(chan extraction)
+	var wg sync.WaitGroup
+	for i := 0; i < max(b.Concurrency, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for record := range jobs {
+				began := time.Now()
+				output, retryable := b.extract(ctx, record)
+				if ctx.Err() != nil {
+					// interrupted, not a failure of the record
+					return
+				}
+				results <- extraction{record, output, time.Since(began), retryable}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var err error
+	encoder := json.NewEncoder(w)
+	for r := range results {
+		if err != nil {
+			continue // drain the workers
+		}
+		if r.retryable && b.Checkpoint != nil {
+			stats.Retry++
+			continue
+		}
+		if err = encoder.Encode(r.output); err == nil && b.Checkpoint != nil {
+			err = b.Checkpoint.Mark(r.record)
+		}
+		if err != nil {
+			cancel()
+			continue
+		}
+		stats.Total++
+		stats.Latency += r.latency
+		if r.output.Error == "" {
+			stats.OK++
+		} else {
+			stats.Failed++
+		}
+	}
+	stats.Elapsed = time.Since(start)
+	if err == nil {
+		err = ctx.Err()
+	}
+	return stats, err
+}
+
+// done reports whether record is in the checkpoint or in the one of a
+// peer.
+func (b *Batch) done(record Record) bool {
+	if b.Checkpoint != nil && b.Checkpoint.Done(record) {
+		return true
+	}
+	for _, peer := range b.Peers {
+		if peer.Done(record) {
+			return true
+		}
+	}
+	return false
+}
+
+// extract runs the chat for one record. retryable is set when the chat
+// failed, rather than the answer being invalid.
+func (b *Batch) extract(ctx context.Context, record Record) (output Output, retryable bool) {
+	output = Output{Line: record.Line, Input: record.Input}
+
+	result, err := b.Client.Chat(ctx, b.Model, b.messages(record.Input), b.Schema)
+	if err != nil {
+		output.Error = err.Error()
+		var invalid *structured.ValidationError
+		if !errors.As(err, &invalid) {
+			return output, true
+		}
+		for _, v := range invalid.Violations {
+			output.Violations = append(output.Violations, v.String())
+		}
+		return output, false
+	}
+	if !json.Valid([]byte(result.Raw)) {
+		output.Error = "invalid JSON answer"
+		return output, false
+	}
+	output.Result = json.RawMessage(result.Raw)
+	output.Model = result.Model
+	output.Confidence = result.Confidence
+	return output, false
+}
+
+func (b *Batch) messages(input string) []api.Message {
+	prompt := input
+	if b.Template != "" {
+		prompt = strings.ReplaceAll(b.Template, "{input}", input)
+	}
+	messages := []api.Message{}
+	if b.System != "" {
+		messages = append(messages, api.Message{Role: "system", Content: b.System})
+	}
+	return append(messages, api.Message{Role: "user", Content: prompt})
+}
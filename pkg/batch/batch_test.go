@@ -0,0 +1,237 @@
+package batch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"01-json-output/internal/mockollama"
+	"01-json-output/pkg/structured"
+)
+
+func TestRun(t *testing.T) {
+	server := mockollama.New(
+		mockollama.Response{Content: `{"name":"chicken"}`},
+		mockollama.Response{Content: `{"size":2}`},
+		mockollama.Response{Status: 500, Error: "model crashed"},
+	)
+	defer server.Close()
+	client, err := structured.NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := &Batch{
+		Client: client,
+		Model:  "granite3-moe:1b",
+		Schema: map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"name": map[string]any{"type": "string"}},
+			"required":   []string{"name"},
+		},
+		Template: "Tell me about {input}",
+	}
+	records := []Record{{1, "chicken"}, {2, "cat"}, {3, "dog"}}
+
+	var out bytes.Buffer
+	stats, err := b.Run(context.Background(), records, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Total != 3 || stats.OK != 1 || stats.Failed != 2 {
+		t.Errorf("stats = %+v", stats)
+	}
+
+	var outputs []Output
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var output Output
+		if err := json.Unmarshal([]byte(line), &output); err != nil {
+			t.Fatal(err)
+		}
+		outputs = append(outputs, output)
+	}
+	if len(outputs) != 3 {
+		t.Fatalf("%d outputs, want 3", len(outputs))
+	}
+	if string(outputs[0].Result) != `{"name":"chicken"}` || outputs[0].Error != "" {
+		t.Errorf("output 1 = %+v", outputs[0])
+	}
+	if outputs[1].Result != nil || len(outputs[1].Violations) == 0 {
+		t.Errorf("output 2 = %+v, want violations", outputs[1])
+	}
+	if !strings.Contains(outputs[2].Error, "model crashed") || outputs[2].Line != 3 {
+		t.Errorf("output 3 = %+v", outputs[2])
+	}
+
+	if got := server.Requests()[0].Messages[0].Content; got != "Tell me about chicken" {
+		t.Errorf("prompt = %q", got)
+	}
+}
+
+func TestRunConcurrency(t *testing.T) {
+	server := mockollama.New(mockollama.Response{Content: `{"name":"chicken"}`})
+	defer server.Close()
+	client, err := structured.NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var records []Record
+	for i := 1; i <= 6; i++ {
+		records = append(records, Record{Line: i, Input: "chicken"})
+	}
+	b := &Batch{Client: client, Model: "granite3-moe:1b", Concurrency: 3, Rate: 50}
+
+	var out bytes.Buffer
+	stats, err := b.Run(context.Background(), records, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Total != 6 || stats.OK != 6 {
+		t.Errorf("stats = %+v", stats)
+	}
+	// 5 requests wait for a token at 50 per second
+	if stats.Elapsed < 90*time.Millisecond {
+		t.Errorf("elapsed = %s, the rate is not limited", stats.Elapsed)
+	}
+	lines := map[int]bool{}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var output Output
+		if err := json.Unmarshal([]byte(line), &output); err != nil {
+			t.Fatal(err)
+		}
+		lines[output.Line] = true
+	}
+	if len(lines) != 6 {
+		t.Errorf("outputs of lines %v, want 1 to 6", lines)
+	}
+}
+
+func TestRunCanceled(t *testing.T) {
+	server := mockollama.New(mockollama.Response{Content: `{"name":"chicken"}`})
+	defer server.Close()
+	client, err := structured.NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	b := &Batch{Client: client, Model: "granite3-moe:1b"}
+	if _, err := b.Run(ctx, []Record{{1, "chicken"}}, io.Discard); err != context.Canceled {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+}
+
+func TestRunCheckpoint(t *testing.T) {
+	server := mockollama.New(
+		mockollama.Response{Content: `{"name":"chicken"}`},
+		mockollama.Response{Status: 503, Error: "connection reset"},
+		mockollama.Response{Content: `{"name":"dog"}`},
+	)
+	defer server.Close()
+	client, err := structured.NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "animals.checkpoint")
+	checkpoint, err := OpenCheckpoint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &Batch{Client: client, Model: "granite3-moe:1b", Checkpoint: checkpoint}
+	records := []Record{{1, "chicken"}, {2, "cat"}}
+	var out bytes.Buffer
+	stats, err := b.Run(context.Background(), records, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Total != 1 || stats.Retry != 1 {
+		t.Errorf("stats = %+v", stats)
+	}
+	checkpoint.Close()
+
+	// the chat error of cat is retried on resume, chicken is skipped
+	if b.Checkpoint, err = OpenCheckpoint(path); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Checkpoint.Close()
+	if b.Checkpoint.Len() != 1 || !b.Checkpoint.Done(Record{1, "chicken"}) {
+		t.Fatalf("checkpoint holds %d record(s)", b.Checkpoint.Len())
+	}
+	records = append(records, Record{3, "dog"})
+	stats, err = b.Run(context.Background(), records, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Skipped != 1 || stats.Total != 2 || stats.OK != 2 {
+		t.Errorf("stats = %+v", stats)
+	}
+	if b.Checkpoint.Len() != 3 {
+		t.Errorf("checkpoint holds %d record(s), want 3", b.Checkpoint.Len())
+	}
+
+	// one output line per record over both runs
+	lines := map[int]int{}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var output Output
+		if err := json.Unmarshal([]byte(line), &output); err != nil {
+			t.Fatal(err)
+		}
+		lines[output.Line]++
+	}
+	if len(lines) != 3 || lines[1] != 1 || lines[2] != 1 || lines[3] != 1 {
+		t.Errorf("output lines = %v\n%s", lines, out.String())
+	}
+}
+
+func TestRunPeers(t *testing.T) {
+	server := mockollama.New(mockollama.Response{Content: `{"name":"dog"}`})
+	defer server.Close()
+	client, err := structured.NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// chicken was done by a peer that left, before the ring changed
+	dir := t.TempDir()
+	departed, err := OpenCheckpoint(filepath.Join(dir, "beta.checkpoint"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := departed.Mark(Record{1, "chicken"}); err != nil {
+		t.Fatal(err)
+	}
+	departed.Close()
+
+	peer, err := ReadCheckpoint(filepath.Join(dir, "beta.checkpoint"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := peer.Mark(Record{2, "dog"}); err == nil {
+		t.Error("Mark on a read-only checkpoint succeeded")
+	}
+	missing, err := ReadCheckpoint(filepath.Join(dir, "gamma.checkpoint"))
+	if err != nil || missing.Len() != 0 {
+		t.Fatalf("missing checkpoint: %d record(s), %v", missing.Len(), err)
+	}
+
+	b := &Batch{Client: client, Model: "granite3-moe:1b", Peers: []*Checkpoint{peer, missing}}
+	var out bytes.Buffer
+	stats, err := b.Run(context.Background(), []Record{{1, "chicken"}, {2, "dog"}}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Skipped != 1 || stats.Total != 1 || stats.OK != 1 {
+		t.Errorf("stats = %+v", stats)
+	}
+	if strings.Contains(out.String(), "chicken") {
+		t.Errorf("output = %s, want the record of the peer skipped", out.String())
+	}
+}
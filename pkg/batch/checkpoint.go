@@ -0,0 +1,114 @@
+package batch
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Checkpoint persists the records done by a batch, one JSON line per
+// record, so an interrupted batch can be resumed without extracting them
+// again.
+type Checkpoint struct {
+	mu   sync.Mutex
+	file *os.File
+	done map[Record]bool
+}
+
+// OpenCheckpoint opens (or creates) the checkpoint file at path and loads
+// the records already done.
+func OpenCheckpoint(path string) (*Checkpoint, error) {
+	done, err := readCheckpoint(path)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &Checkpoint{file: file, done: done}, nil
+}
+
+// ReadCheckpoint loads the records done of the checkpoint file at path,
+// read-only (e.g. the checkpoint of another instance, see Batch.Peers). A
+// missing file has no records.
+func ReadCheckpoint(path string) (*Checkpoint, error) {
+	done, err := readCheckpoint(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Checkpoint{done: done}, nil
+}
+
+func readCheckpoint(path string) (map[Record]bool, error) {
+	done := map[Record]bool{}
+	data, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return done, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer data.Close()
+	scanner := bufio.NewScanner(data)
+	for scanner.Scan() {
+		var record checkpointLine
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			// the last line of an interrupted write
+			continue
+		}
+		done[Record{Line: record.Line, Input: record.Input}] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("batch: checkpoint: %w", err)
+	}
+	return done, nil
+}
+
+type checkpointLine struct {
+	Line  int    `json:"line"`
+	Input string `json:"input"`
+}
+
+// Done reports whether record was done, with the same line and input.
+func (c *Checkpoint) Done(record Record) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.done[record]
+}
+
+// Len returns the number of records done.
+func (c *Checkpoint) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.done)
+}
+
+// Mark records that record is done, and syncs the file.
+func (c *Checkpoint) Mark(record Record) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.file == nil {
+		return errors.New("batch: checkpoint: read-only")
+	}
+	line, err := json.Marshal(checkpointLine{Line: record.Line, Input: record.Input})
+	if err != nil {
+		return err
+	}
+	if _, err := c.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	c.done[record] = true
+	return c.file.Sync()
+}
+
+// Close closes the checkpoint file.
+func (c *Checkpoint) Close() error {
+	if c.file == nil {
+		return nil
+	}
+	return c.file.Close()
+}
@@ -0,0 +1,76 @@
+package batch
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// DefaultReplicas is the number of points of each peer on a Ring.
+const DefaultReplicas = 64
+
+// Ring partitions records between peers by consistent hashing: when a peer
+// joins or leaves, only the records of that peer move. The peers are a static
+// list: a membership change means building a new Ring, and the records
+// already done by their previous owner are skipped with Batch.Peers.
+type Ring struct {
+	points []uint32
+	owners map[uint32]string
+}
+
+// NewRing returns the ring of peers, each one having replicas points
+// (DefaultReplicas when 0).
+func NewRing(peers []string, replicas int) (*Ring, error) {
+	if len(peers) == 0 {
+		return nil, fmt.Errorf("batch: no peers")
+	}
+	if replicas <= 0 {
+		replicas = DefaultReplicas
+	}
+	r := &Ring{owners: map[uint32]string{}}
+	for _, peer := range peers {
+		if peer == "" {
+			return nil, fmt.Errorf("batch: empty peer name")
+		}
+		for i := 0; i < replicas; i++ {
+			point := hash(peer + "#" + strconv.Itoa(i))
+			if _, taken := r.owners[point]; taken {
+				continue
+			}
+			r.owners[point] = peer
+			r.points = append(r.points, point)
+		}
+	}
+	sort.Slice(r.points, func(i, j int) bool { return r.points[i] < r.points[j] })
+	return r, nil
+}
+
+// Owner returns the peer of key.
+func (r *Ring) Owner(key string) string {
+	h := hash(key)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= h })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.owners[r.points[i]]
+}
+
+// Partition returns the records of peer. The key of a record is its line
+// and input, so every peer must read the same input file.
+func (r *Ring) Partition(records []Record, peer string) []Record {
+	var mine []Record
+	for _, record := range records {
+		if r.Owner(strconv.Itoa(record.Line)+":"+record.Input) == peer {
+			mine = append(mine, record)
+		}
+	}
+	return mine
+}
+
+// hash spreads similar keys (numbered lines, peer points) evenly.
+func hash(s string) uint32 {
+	sum := sha256.Sum256([]byte(s))
+	return binary.BigEndian.Uint32(sum[:4])
+}
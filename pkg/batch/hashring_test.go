@@ -0,0 +1,58 @@
+package batch
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRingPartition(t *testing.T) {
+	var records []Record
+	for i := 1; i <= 1000; i++ {
+		records = append(records, Record{Line: i, Input: fmt.Sprint("animal ", i)})
+	}
+	peers := []string{"alpha", "beta", "gamma"}
+	ring, err := NewRing(peers, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	owners := map[Record]string{}
+	for _, peer := range peers {
+		part := ring.Partition(records, peer)
+		// roughly a third each
+		if len(part) < 200 || len(part) > 470 {
+			t.Errorf("%s has %d records", peer, len(part))
+		}
+		for _, record := range part {
+			if owner, ok := owners[record]; ok {
+				t.Fatalf("record %d owned by %s and %s", record.Line, owner, peer)
+			}
+			owners[record] = peer
+		}
+	}
+	if len(owners) != len(records) {
+		t.Fatalf("%d records partitioned, want %d", len(owners), len(records))
+	}
+
+	// a new peer only takes records, the others don't move
+	bigger, err := NewRing(append(peers, "delta"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, peer := range peers {
+		for _, record := range bigger.Partition(records, peer) {
+			if owners[record] != peer {
+				t.Errorf("record %d moved from %s to %s", record.Line, owners[record], peer)
+			}
+		}
+	}
+}
+
+func TestRingErrors(t *testing.T) {
+	if _, err := NewRing(nil, 0); err == nil {
+		t.Error("no peers: no error")
+	}
+	if _, err := NewRing([]string{"alpha", ""}, 0); err == nil {
+		t.Error("empty peer: no error")
+	}
+}
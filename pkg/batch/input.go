@@ -0,0 +1,126 @@
+package batch
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Record is one input of a batch.
+type Record struct {
+	// Line is the line number of the record in the input file, from 1.
+	Line  int
+	Input string
+}
+
+// ReadFile reads the records of the file at path, depending on its
+// extension (see ReadCSV and ReadJSONL), or one record per non-empty line.
+// column selects the CSV column or the JSONL field holding the input.
+func ReadFile(path, column string) ([]Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return ReadCSV(f, column)
+	case ".jsonl", ".ndjson":
+		return ReadJSONL(f, column)
+	default:
+		return ReadLines(f)
+	}
+}
+
+// ReadLines reads one record per non-empty line of r.
+func ReadLines(r io.Reader) ([]Record, error) {
+	var records []Record
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		if input := strings.TrimSpace(scanner.Text()); input != "" {
+			records = append(records, Record{Line: line, Input: input})
+		}
+	}
+	return records, scanner.Err()
+}
+
+// ReadCSV reads the records of a CSV file with a header line. The input is
+// the value of column, or of the first column when column is empty.
+func ReadCSV(r io.Reader, column string) ([]Record, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("batch: CSV header: %w", err)
+	}
+	index := 0
+	if column != "" {
+		index = -1
+		for i, name := range header {
+			if strings.TrimSpace(name) == column {
+				index = i
+			}
+		}
+		if index < 0 {
+			return nil, fmt.Errorf("batch: no CSV column %q", column)
+		}
+	}
+
+	var records []Record
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("batch: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		if index >= len(row) {
+			return nil, fmt.Errorf("batch: line %d: no column %d", line, index+1)
+		}
+		if input := strings.TrimSpace(row[index]); input != "" {
+			records = append(records, Record{Line: line, Input: input})
+		}
+	}
+}
+
+// ReadJSONL reads one record per line of JSON: either a string, or an
+// object whose column field (default "prompt") is the input.
+func ReadJSONL(r io.Reader, column string) ([]Record, error) {
+	if column == "" {
+		column = "prompt"
+	}
+	var records []Record
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var value any
+		if err := json.Unmarshal([]byte(text), &value); err != nil {
+			return nil, fmt.Errorf("batch: line %d: %w", line, err)
+		}
+		var input string
+		switch value := value.(type) {
+		case string:
+			input = value
+		case map[string]any:
+			s, ok := value[column].(string)
+			if !ok {
+				return nil, fmt.Errorf("batch: line %d: no string field %q", line, column)
+			}
+			input = s
+		default:
+			return nil, fmt.Errorf("batch: line %d: a string or an object is expected", line)
+		}
+		records = append(records, Record{Line: line, Input: input})
+	}
+	return records, scanner.Err()
+}
@@ -0,0 +1,63 @@
+package batch
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadInputs(t *testing.T) {
+	tests := []struct {
+		name string
+		read func() ([]Record, error)
+		want []Record
+	}{
+		{
+			"lines",
+			func() ([]Record, error) { return ReadLines(strings.NewReader("chicken\n\n  cat \n")) },
+			[]Record{{1, "chicken"}, {3, "cat"}},
+		},
+		{
+			"csv first column",
+			func() ([]Record, error) { return ReadCSV(strings.NewReader("name,legs\nchicken,2\ncat,4\n"), "") },
+			[]Record{{2, "chicken"}, {3, "cat"}},
+		},
+		{
+			"csv column",
+			func() ([]Record, error) {
+				return ReadCSV(strings.NewReader("id,prompt\n1,\"Tell me about chicken, please\"\n2,\n"), "prompt")
+			},
+			[]Record{{2, "Tell me about chicken, please"}},
+		},
+		{
+			"jsonl",
+			func() ([]Record, error) {
+				return ReadJSONL(strings.NewReader("\"chicken\"\n{\"prompt\":\"cat\",\"id\":2}\n"), "")
+			},
+			[]Record{{1, "chicken"}, {2, "cat"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("records = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadInputsErrors(t *testing.T) {
+	if _, err := ReadCSV(strings.NewReader("name\nchicken\n"), "prompt"); err == nil {
+		t.Error("missing CSV column: no error")
+	}
+	if _, err := ReadJSONL(strings.NewReader("{\"name\":\"chicken\"}\n"), ""); err == nil {
+		t.Error("missing JSONL field: no error")
+	}
+	if _, err := ReadJSONL(strings.NewReader("chicken\n"), ""); err == nil {
+		t.Error("invalid JSONL line: no error")
+	}
+}
@@ -0,0 +1,187 @@
+// Package schema is a fluent builder of JSON schemas for structured
+// outputs:
+//
+//	animal, err := schema.Object().
+//		Prop("scientific_name", schema.String()).
+//		Prop("countries", schema.ArrayOf(schema.String())).
+//		Required("scientific_name", "countries").
+//		Build()
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"01-json-output/pkg/structured"
+)
+
+// Schema is a JSON schema under construction.
+type Schema struct {
+	keywords   map[string]any
+	properties map[string]*Schema
+	items      *Schema
+	required   []string
+	errs       []error
+}
+
+func typed(t string) *Schema {
+	return &Schema{keywords: map[string]any{"type": t}}
+}
+
+// String returns a string schema.
+func String() *Schema { return typed("string") }
+
+// Number returns a number schema.
+func Number() *Schema { return typed("number") }
+
+// Integer returns an integer schema.
+func Integer() *Schema { return typed("integer") }
+
+// Boolean returns a boolean schema.
+func Boolean() *Schema { return typed("boolean") }
+
+// Object returns an object schema, to complete with Prop and Required.
+func Object() *Schema {
+	s := typed("object")
+	s.properties = map[string]*Schema{}
+	return s
+}
+
+// ArrayOf returns an array schema whose items match items.
+func ArrayOf(items *Schema) *Schema {
+	s := typed("array")
+	s.items = items
+	return s
+}
+
+// Prop adds the property name to an object schema.
+func (s *Schema) Prop(name string, property *Schema) *Schema {
+	if s.properties == nil {
+		s.errs = append(s.errs, fmt.Errorf("property %q added to a %v schema", name, s.keywords["type"]))
+		return s
+	}
+	if property == nil {
+		s.errs = append(s.errs, fmt.Errorf("property %q has no schema", name))
+		return s
+	}
+	s.properties[name] = property
+	return s
+}
+
+// Required marks properties of an object schema as required.
+func (s *Schema) Required(names ...string) *Schema {
+	s.required = append(s.required, names...)
+	return s
+}
+
+// Title sets the title of the schema.
+func (s *Schema) Title(title string) *Schema {
+	s.keywords["title"] = title
+	return s
+}
+
+// Description sets the description of the schema, small models use it as
+// a hint about the expected value.
+func (s *Schema) Description(description string) *Schema {
+	s.keywords["description"] = description
+	return s
+}
+
+// Minimum sets the minimum of a number or integer schema.
+func (s *Schema) Minimum(minimum float64) *Schema {
+	s.keywords["minimum"] = minimum
+	return s
+}
+
+// Maximum sets the maximum of a number or integer schema.
+func (s *Schema) Maximum(maximum float64) *Schema {
+	s.keywords["maximum"] = maximum
+	return s
+}
+
+// Enum restricts the schema to values.
+func (s *Schema) Enum(values ...any) *Schema {
+	s.keywords["enum"] = values
+	return s
+}
+
+// Pattern sets the regular expression a string must match.
+func (s *Schema) Pattern(pattern string) *Schema {
+	s.keywords["pattern"] = pattern
+	return s
+}
+
+// MinLength sets the minimum number of characters of a string.
+func (s *Schema) MinLength(n int) *Schema {
+	s.keywords["minLength"] = n
+	return s
+}
+
+// MaxLength sets the maximum number of characters of a string.
+func (s *Schema) MaxLength(n int) *Schema {
+	s.keywords["maxLength"] = n
+	return s
+}
+
+// Format sets the format of a string, like "date-time" or "date".
+func (s *Schema) Format(format string) *Schema {
+	s.keywords["format"] = format
+	return s
+}
+
+// Map returns the schema as a map, like the ones of structured.SchemaOf.
+func (s *Schema) Map() map[string]any {
+	m := map[string]any{}
+	for k, v := range s.keywords {
+		m[k] = v
+	}
+	if s.properties != nil {
+		properties := map[string]any{}
+		for name, p := range s.properties {
+			properties[name] = p.Map()
+		}
+		m["properties"] = properties
+	}
+	if len(s.required) > 0 {
+		m["required"] = s.required
+	}
+	if s.items != nil {
+		m["items"] = s.items.Map()
+	}
+	return m
+}
+
+// Build checks the schema with structured.CheckSchema and returns it as
+// JSON, ready to be used as a chat format.
+func (s *Schema) Build() (json.RawMessage, error) {
+	if err := s.err(); err != nil {
+		return nil, fmt.Errorf("schema: %w", err)
+	}
+	m := s.Map()
+	if err := structured.CheckSchema(m); err != nil {
+		return nil, err
+	}
+	return json.Marshal(m)
+}
+
+// err returns the first error recorded while building s or its children.
+func (s *Schema) err() error {
+	if len(s.errs) > 0 {
+		return s.errs[0]
+	}
+	names := make([]string, 0, len(s.properties))
+	for name := range s.properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := s.properties[name].err(); err != nil {
+			return err
+		}
+	}
+	if s.items != nil {
+		return s.items.err()
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package schema
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestBuild(t *testing.T) {
+	animal, err := Object().
+		Title("Animal").
+		Prop("scientific_name", String().Pattern("^[A-Z]")).
+		Prop("diet", String().Enum("herbivore", "carnivore")).
+		Prop("legs", Integer().Minimum(0).Maximum(8)).
+		Prop("countries", ArrayOf(Object().Prop("name", String()).Required("name"))).
+		Required("scientific_name", "countries").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"properties":{"countries":{"items":{"properties":{"name":{"type":"string"}},"required":["name"],"type":"object"},"type":"array"},` +
+		`"diet":{"enum":["herbivore","carnivore"],"type":"string"},"legs":{"maximum":8,"minimum":0,"type":"integer"},` +
+		`"scientific_name":{"pattern":"^[A-Z]","type":"string"}},"required":["scientific_name","countries"],"title":"Animal","type":"object"}`
+	if string(animal) != want {
+		t.Errorf("schema = %s", animal)
+	}
+	if !json.Valid(animal) {
+		t.Error("invalid JSON")
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema *Schema
+		want   string
+	}{
+		{"prop on a string", Object().Prop("name", String().Prop("first", String())), `property "first" added to a string schema`},
+		{"nil property", Object().Prop("name", nil), `property "name" has no schema`},
+		{"unknown required", Object().Prop("name", String()).Required("nme"), `required field "nme" is not a property`},
+		{"bad pattern", String().Pattern("[a-"), "invalid pattern"},
+	}
+	for _, tt := range tests {
+		_, err := tt.schema.Build()
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package structured
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+// Cache stores the results of Chat and Extract by request, see
+// Client.Cache. Caching is best effort: implementations ignore the errors
+// of Put.
+type Cache interface {
+	Get(key string) (*Result, bool)
+	Put(key string, result *Result)
+}
+
+// cacheKey identifies a request: the same model, messages, options, schema,
+// configured strategy, fallbacks, voting, strictness and capabilities of the
+// model get the same key.
+func (c *Client) cacheKey(model string, messages []api.Message, schema any) (string, error) {
+	strategy := ""
+	if s, ok := c.Strategies[schemaTitle(schema)]; ok {
+		strategy = s.Name()
+	} else if c.Strategy != nil {
+		strategy = c.Strategy.Name()
+	}
+	var capabilities *ModelCapabilities
+	if caps, ok := c.Capabilities.Lookup(model); ok {
+		capabilities = &caps
+	}
+	request, err := json.Marshal(struct {
+		Model        string             `json:"model"`
+		Messages     []api.Message      `json:"messages"`
+		Options      map[string]any     `json:"options"`
+		Schema       any                `json:"schema"`
+		Strategy     string             `json:"strategy"`
+		Fallbacks    []string           `json:"fallbacks,omitempty"`
+		Voting       *Voting            `json:"voting,omitempty"`
+		Strict       bool               `json:"strict,omitempty"`
+		Capabilities *ModelCapabilities `json:"capabilities,omitempty"`
+	}{model, messages, c.Options.ToMap(), schema, strategy, c.Fallbacks, c.Voting, c.Strict, capabilities})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(request)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// MemoryCache is a Cache in memory, for the life of the process.
+type MemoryCache struct {
+	// TTL is the time results are kept, forever when 0.
+	TTL time.Duration
+
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+}
+
+type memoryEntry struct {
+	result  Result
+	created time.Time
+}
+
+// NewMemoryCache returns an empty MemoryCache.
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{entries: map[string]memoryEntry{}}
+}
+
+func (m *MemoryCache) Get(key string) (*Result, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if expired(entry.created, m.TTL) {
+		delete(m.entries, key)
+		return nil, false
+	}
+	return &entry.result, true
+}
+
+func (m *MemoryCache) Put(key string, result *Result) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[key] = memoryEntry{result: *result, created: time.Now()}
+}
+
+// expired reports whether an entry created at created is older than ttl.
+func expired(created time.Time, ttl time.Duration) bool {
+	return ttl > 0 && time.Since(created) > ttl
+}
+
+// FileCache is a Cache storing one JSON file per result in a directory, so
+// results survive the process (e.g. between runs of a demo).
+type FileCache struct {
+	Dir string
+	// TTL is the time results are kept, forever when 0. Expired files are
+	// removed when read, see also Prune.
+	TTL time.Duration
+}
+
+// CacheEntry is the content of a FileCache file.
+type CacheEntry struct {
+	Created     time.Time          `json:"created"`
+	Raw         string             `json:"raw"`
+	Model       string             `json:"model,omitempty"`
+	Strategy    string             `json:"strategy"`
+	Enforcement Enforcement        `json:"enforcement"`
+	Confidence  map[string]float64 `json:"confidence,omitempty"`
+}
+
+func (f *FileCache) path(key string) string {
+	return filepath.Join(f.Dir, key+".json")
+}
+
+func (f *FileCache) Get(key string) (*Result, bool) {
+	data, err := os.ReadFile(f.path(key))
+	if err != nil {
+		return nil, false
+	}
+	var entry CacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, false
+	}
+	if expired(entry.Created, f.TTL) {
+		os.Remove(f.path(key))
+		return nil, false
+	}
+	return &Result{Raw: entry.Raw, Model: entry.Model, Strategy: entry.Strategy, Enforcement: entry.Enforcement, Confidence: entry.Confidence}, true
+}
+
+func (f *FileCache) Put(key string, result *Result) {
+	data, err := json.Marshal(CacheEntry{
+		Created:     time.Now(),
+		Raw:         result.Raw,
+		Model:       result.Model,
+		Strategy:    result.Strategy,
+		Enforcement: result.Enforcement,
+		Confidence:  result.Confidence,
+	})
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(f.Dir, 0o755); err != nil {
+		return
+	}
+	// written aside then renamed, so a reader never gets a partial file
+	tmp, err := os.CreateTemp(f.Dir, key+".*.tmp")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	if err := os.Rename(tmp.Name(), f.path(key)); err != nil {
+		os.Remove(tmp.Name())
+	}
+}
@@ -0,0 +1,82 @@
+package structured
+
+import (
+	"context"
+	"testing"
+
+	"01-json-output/internal/mockollama"
+)
+
+func TestCache(t *testing.T) {
+	caches := []struct {
+		name  string
+		cache func(t *testing.T) Cache
+	}{
+		{"memory", func(t *testing.T) Cache { return NewMemoryCache() }},
+		{"file", func(t *testing.T) Cache { return &FileCache{Dir: t.TempDir()} }},
+	}
+	for _, tt := range caches {
+		t.Run(tt.name, func(t *testing.T) {
+			client, server := newTestClient(t,
+				mockollama.Response{Content: `{"name":"chicken"}`},
+				mockollama.Response{Content: `{"name":"cat"}`},
+			)
+			client.Cache = tt.cache(t)
+			ctx := context.Background()
+
+			first, err := client.Chat(ctx, "granite3-moe:1b", userMessages("chicken"), nameSchema())
+			if err != nil {
+				t.Fatal(err)
+			}
+			second, err := client.Chat(ctx, "granite3-moe:1b", userMessages("chicken"), nameSchema())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if second.Raw != first.Raw || second.Strategy != first.Strategy || second.Enforcement != first.Enforcement {
+				t.Errorf("cached result = %+v, want %+v", second, first)
+			}
+			if !second.Metrics.Cached || second.Metrics.Requests != 0 {
+				t.Errorf("cached metrics = %+v", second.Metrics)
+			}
+			if n := len(server.Requests()); n != 1 {
+				t.Errorf("%d chat requests, want 1", n)
+			}
+
+			// other options, other request
+			client.Options.Seed = Int(42)
+			third, err := client.Chat(ctx, "granite3-moe:1b", userMessages("chicken"), nameSchema())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if third.Raw != `{"name":"cat"}` {
+				t.Errorf("answer = %s, want a new answer", third.Raw)
+			}
+		})
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	client, _ := newTestClient(t)
+	key := func() string {
+		t.Helper()
+		k, err := client.cacheKey("granite3-moe:1b", userMessages("chicken"), nameSchema())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return k
+	}
+
+	keys := map[string]string{"default": key()}
+	client.Strict = true
+	keys["strict"] = key()
+	client.Capabilities = Capabilities{"granite3-moe": {JSONHint: true}}
+	keys["capabilities"] = key()
+
+	seen := map[string]string{}
+	for name, k := range keys {
+		if other, ok := seen[k]; ok {
+			t.Errorf("%s and %s have the same key", name, other)
+		}
+		seen[k] = name
+	}
+}
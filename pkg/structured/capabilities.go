@@ -0,0 +1,145 @@
+package structured
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+)
+
+// ModelCapabilities describes the quirks of a model, used to adapt the
+// prompt and the strategy.
+type ModelCapabilities struct {
+	// JSONHint adds JSONHintPrompt as a system message: some models don't
+	// stick to JSON without being told explicitly.
+	JSONHint bool `json:"json_hint,omitempty"`
+	// PoorNestedArrays: the model struggles with arrays of objects or
+	// arrays, such schemas are queried with the PerField strategy.
+	PoorNestedArrays bool `json:"poor_nested_arrays,omitempty"`
+	// Tools: the model supports tool calling, needed by ToolFill.
+	Tools bool `json:"tools,omitempty"`
+	// Strategy is the name of the preferred strategy of the model.
+	Strategy string `json:"strategy,omitempty"`
+	// Simplify marks a low-capability model: schemas are simplified for it
+	// and split in parts of MaxProperties properties (see Simplified).
+	Simplify      bool `json:"simplify,omitempty"`
+	MaxProperties int  `json:"max_properties,omitempty"`
+}
+
+// Capabilities are model capabilities by model name. A name without tag
+// ("qwen2.5") applies to all the tags of the model.
+type Capabilities map[string]ModelCapabilities
+
+// DefaultCapabilities are the known quirks of some small models.
+var DefaultCapabilities = Capabilities{
+	"granite3-moe": {Tools: true},
+	// qwen2.5:0.5b sometimes freezes with structured outputs
+	"qwen2.5:0.5b": {Tools: true, PoorNestedArrays: true, Strategy: "prompted", Simplify: true, MaxProperties: 4},
+	"qwen2.5":      {Tools: true},
+	"llama3.2":     {Tools: true},
+	"deepseek-r1":  {JSONHint: true},
+}
+
+// JSONHintPrompt is the system message added for models with JSONHint.
+var JSONHintPrompt = "Respond with JSON only."
+
+// LoadCapabilities reads capabilities from a JSON file:
+//
+//	{"qwen2.5:0.5b": {"strategy": "two-pass", "poor_nested_arrays": true}}
+func LoadCapabilities(path string) (Capabilities, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var capabilities Capabilities
+	if err := json.Unmarshal(data, &capabilities); err != nil {
+		return nil, fmt.Errorf("structured: %s: %w", path, err)
+	}
+	return capabilities, nil
+}
+
+// Merge returns a copy of cs with the entries of other added or replaced.
+func (cs Capabilities) Merge(other Capabilities) Capabilities {
+	merged := Capabilities{}
+	for model, c := range cs {
+		merged[model] = c
+	}
+	for model, c := range other {
+		merged[model] = c
+	}
+	return merged
+}
+
+// Lookup returns the capabilities of model, matched by full name first,
+// then by name without tag.
+func (cs Capabilities) Lookup(model string) (ModelCapabilities, bool) {
+	if c, ok := cs[model]; ok {
+		return c, true
+	}
+	name, _, _ := strings.Cut(model, ":")
+	c, ok := cs[name]
+	return c, ok
+}
+
+// adapt applies the capabilities of model to the selected strategy and
+// to the messages.
+func (c *Client) adapt(model string, schema any, strategy Strategy, explicit bool, messages []api.Message) (Strategy, []api.Message, error) {
+	caps, ok := c.Capabilities.Lookup(model)
+	if !ok {
+		return strategy, messages, nil
+	}
+
+	// only the default strategy is replaced, not an explicit choice or the
+	// fallback for servers without JSON schema support
+	if _, direct := strategy.(Direct); direct && !explicit {
+		switch {
+		case caps.PoorNestedArrays && hasNestedArrays(schema):
+			strategy = PerField{}
+		case caps.Strategy != "":
+			s, err := StrategyByName(caps.Strategy)
+			if err != nil {
+				return nil, nil, err
+			}
+			strategy = s
+		}
+	}
+	if _, toolFill := strategy.(ToolFill); toolFill && !caps.Tools {
+		strategy = Direct{}
+	}
+	if caps.Simplify && schema != nil {
+		strategy = Simplified{Inner: strategy, MaxProperties: caps.MaxProperties}
+	}
+
+	if caps.JSONHint {
+		messages = append([]api.Message{{Role: "system", Content: JSONHintPrompt}}, messages...)
+	}
+	return strategy, messages, nil
+}
+
+// hasNestedArrays tells whether schema has an array of arrays or objects.
+func hasNestedArrays(schema any) bool {
+	s, err := schemaMap(schema)
+	if err != nil {
+		return false
+	}
+	var walk func(s map[string]any, inArray bool) bool
+	walk = func(s map[string]any, inArray bool) bool {
+		t, _ := s["type"].(string)
+		if inArray && (t == "array" || t == "object") {
+			return true
+		}
+		if items, ok := s["items"].(map[string]any); ok && walk(items, true) {
+			return true
+		}
+		properties, _ := s["properties"].(map[string]any)
+		for _, p := range properties {
+			if p, ok := p.(map[string]any); ok && walk(p, false) {
+				return true
+			}
+		}
+		return false
+	}
+	return walk(s, false)
+}
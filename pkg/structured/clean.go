@@ -0,0 +1,287 @@
+package structured
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// ThinkingTags delimit the reasoning segments of reasoning models
+// (deepseek-r1, qwq...) removed by StripThinking.
+var ThinkingTags = [][2]string{
+	{"<think>", "</think>"},
+	{"<thinking>", "</thinking>"},
+	{"<reasoning>", "</reasoning>"},
+}
+
+// StripThinking removes the reasoning segments of text. An unclosed
+// segment runs to the end of the text. Once the JSON starts (at the first
+// { or [ outside a segment), the tags inside JSON strings are kept: they
+// are part of the answer.
+func StripThinking(text string) string {
+	var out strings.Builder
+	inJSON, inString, escaped := false, false, false
+	for i := 0; i < len(text); i++ {
+		ch := text[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case ch == '\\':
+				escaped = true
+			case ch == '"':
+				inString = false
+			}
+			out.WriteByte(ch)
+			continue
+		}
+		if end, ok := thinkingSegment(text, i); ok {
+			if end < 0 {
+				break
+			}
+			i = end - 1
+			continue
+		}
+		switch {
+		case ch == '{' || ch == '[':
+			inJSON = true
+		case ch == '"' && inJSON:
+			inString = true
+		}
+		out.WriteByte(ch)
+	}
+	return out.String()
+}
+
+// thinkingSegment reports whether a reasoning segment starts at i in text,
+// and returns the index following it, -1 when it is not closed.
+func thinkingSegment(text string, i int) (int, bool) {
+	for _, tags := range ThinkingTags {
+		if !strings.HasPrefix(text[i:], tags[0]) {
+			continue
+		}
+		end := strings.Index(text[i:], tags[1])
+		if end < 0 {
+			return -1, true
+		}
+		return i + end + len(tags[1]), true
+	}
+	return 0, false
+}
+
+// FirstJSONDocument returns the first complete and valid JSON object or
+// array of text. Arrays and objects nested in an invalid document are not
+// considered.
+func FirstJSONDocument(text string) (string, bool) {
+	for i := 0; i < len(text); i++ {
+		if text[i] != '{' && text[i] != '[' {
+			continue
+		}
+		var document json.RawMessage
+		if err := json.NewDecoder(strings.NewReader(text[i:])).Decode(&document); err == nil {
+			return string(document), true
+		}
+		// skip the invalid document
+		end := closingBracket(text, i)
+		if end < 0 {
+			break
+		}
+		i = end
+	}
+	return "", false
+}
+
+// closingBracket returns the index of the bracket closing the one at start,
+// or -1.
+func closingBracket(text string, start int) int {
+	depth := 0
+	inString, escaped := false, false
+	for i := start; i < len(text); i++ {
+		ch := text[i]
+		switch {
+		case inString && escaped:
+			escaped = false
+		case inString && ch == '\\':
+			escaped = true
+		case ch == '"':
+			inString = !inString
+		case inString:
+		case ch == '{' || ch == '[':
+			depth++
+		case ch == '}' || ch == ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// CleanJSON makes a best effort to turn a model answer into valid JSON
+// before it is decoded. A valid answer is returned as is, otherwise:
+//
+//   - reasoning segments (<think>...</think>) are removed, except inside
+//     JSON strings
+//   - the first valid JSON document is returned as is, otherwise:
+//   - the content of a ```json Markdown fence is extracted
+//   - text before the first { or [ and after the matching bracket is dropped
+//   - control characters are removed (escaped inside strings)
+//   - trailing commas are removed
+//
+// A truncated answer stays invalid: its values may be cut short. See
+// CloseTruncatedJSON to close it anyway.
+//
+// Chat, Extract and StreamJSONChat apply it unless Client.Strict is set.
+func CleanJSON(raw string) string {
+	if text := strings.TrimSpace(raw); json.Valid([]byte(text)) {
+		return text
+	}
+	text := strings.TrimSpace(StripThinking(raw))
+	if document, ok := FirstJSONDocument(text); ok {
+		return document
+	}
+	text = stripFence(text)
+
+	start := strings.IndexAny(text, "{[")
+	if start < 0 {
+		return text
+	}
+	return scanJSON(text[start:]).out
+}
+
+// CloseTruncatedJSON cleans raw with CleanJSON, then closes the strings,
+// arrays and objects left open by a truncated answer. The last value may be
+// cut short ("Ir" for "Iran"): it is for the callers accepting partial data,
+// the client never applies it.
+func CloseTruncatedJSON(raw string) string {
+	text := CleanJSON(raw)
+	if json.Valid([]byte(text)) || !strings.HasPrefix(text, "{") && !strings.HasPrefix(text, "[") {
+		return text
+	}
+	scan := scanJSON(text)
+	repaired := scan.out
+	if scan.inString {
+		if scan.escaped {
+			repaired = repaired[:len(repaired)-1]
+		}
+		repaired += `"`
+	}
+	repaired = strings.TrimRight(repaired, " \t\r\n")
+	switch {
+	case strings.HasSuffix(repaired, ","):
+		repaired = repaired[:len(repaired)-1]
+	case strings.HasSuffix(repaired, ":"):
+		repaired += "null"
+	}
+	for i := len(scan.stack) - 1; i >= 0; i-- {
+		if scan.stack[i] == '{' {
+			repaired += "}"
+		} else {
+			repaired += "]"
+		}
+	}
+	return repaired
+}
+
+// jsonScan is the result of scanJSON: the cleaned document and what is
+// left open at its end.
+type jsonScan struct {
+	out      string
+	stack    []byte
+	inString bool
+	escaped  bool
+}
+
+// scanJSON cleans the document starting at the beginning of text, up to
+// the bracket closing the first one: control characters and trailing
+// commas are removed.
+func scanJSON(text string) jsonScan {
+	var (
+		out      strings.Builder
+		stack    []byte
+		inString bool
+		escaped  bool
+	)
+	for i := 0; i < len(text); i++ {
+		ch := text[i]
+
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+				out.WriteByte(ch)
+			case ch == '\\':
+				escaped = true
+				out.WriteByte(ch)
+			case ch == '"':
+				inString = false
+				out.WriteByte(ch)
+			case ch == '\n':
+				out.WriteString(`\n`)
+			case ch == '\t':
+				out.WriteString(`\t`)
+			case ch == '\r':
+				out.WriteString(`\r`)
+			case ch < 0x20:
+				// other control characters are dropped
+			default:
+				out.WriteByte(ch)
+			}
+			continue
+		}
+
+		switch {
+		case ch == '"':
+			inString = true
+		case ch == '{' || ch == '[':
+			stack = append(stack, ch)
+		case ch == '}' || ch == ']':
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case ch == ',':
+			if next := nextSignificant(text, i+1); next == '}' || next == ']' {
+				continue
+			}
+		case ch < 0x20 && !isSpace(ch):
+			continue
+		}
+		out.WriteByte(ch)
+
+		if len(stack) == 0 {
+			// end of the document, the rest is chatter
+			break
+		}
+	}
+	return jsonScan{out: out.String(), stack: stack, inString: inString, escaped: escaped}
+}
+
+// stripFence returns the content of the first Markdown code fence of text,
+// or text when there is none.
+func stripFence(text string) string {
+	start := strings.Index(text, "```")
+	if start < 0 {
+		return text
+	}
+	content := text[start+3:]
+	// skip the language of the fence (```json)
+	if newline := strings.IndexByte(content, '\n'); newline >= 0 {
+		if info := strings.TrimSpace(content[:newline]); !strings.ContainsAny(info, "{[") {
+			content = content[newline+1:]
+		}
+	}
+	if end := strings.Index(content, "```"); end >= 0 {
+		content = content[:end]
+	}
+	return strings.TrimSpace(content)
+}
+
+// nextSignificant returns the first non-space byte of text from i, or 0.
+func nextSignificant(text string, i int) byte {
+	for ; i < len(text); i++ {
+		if !isSpace(text[i]) {
+			return text[i]
+		}
+	}
+	return 0
+}
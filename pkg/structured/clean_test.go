@@ -0,0 +1,140 @@
+package structured
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"01-json-output/internal/mockollama"
+)
+
+func TestCleanJSON(t *testing.T) {
+	tests := []struct {
+		name, raw, want string
+	}{
+		{"already valid", `{"a": 1}`, `{"a": 1}`},
+		{"fence", "```json\n{\"a\": 1}\n```", `{"a": 1}`},
+		{"fence with chatter", "Here is the JSON:\n```json\n{\"a\": [1, 2]}\n```\nHope it helps!", `{"a": [1, 2]}`},
+		{"chatter", `Sure! {"a": 1} is the answer.`, `{"a": 1}`},
+		{"trailing commas", `{"a": [1, 2,], "b": 3,}`, `{"a": [1, 2], "b": 3}`},
+		{"comma in a string", `{"a": "x,}"}`, `{"a": "x,}"}`},
+		{"control characters", "{\"a\": \"line\nbreak\x01\"\x02}", `{"a": "line\nbreak"}`},
+		{"no JSON", `I don't know`, `I don't know`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CleanJSON(tt.raw)
+			if got != tt.want {
+				t.Errorf("CleanJSON(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+			if tt.name != "no JSON" && !json.Valid([]byte(got)) {
+				t.Errorf("%q is not valid JSON", got)
+			}
+		})
+	}
+}
+
+func TestCloseTruncatedJSON(t *testing.T) {
+	tests := []struct {
+		name, raw, want string
+	}{
+		{"truncated string", `{"a": "Gall`, `{"a": "Gall"}`},
+		{"truncated array", `{"a": ["China", "Iran",`, `{"a": ["China", "Iran"]}`},
+		{"truncated key", `{"a": 1, "b":`, `{"a": 1, "b":null}`},
+		{"complete", "```json\n{\"a\": 1,}\n```", `{"a": 1}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if json.Valid([]byte(CleanJSON(tt.raw))) != (tt.name == "complete") {
+				t.Errorf("CleanJSON(%q) = %q", tt.raw, CleanJSON(tt.raw))
+			}
+			if got := CloseTruncatedJSON(tt.raw); got != tt.want {
+				t.Errorf("CloseTruncatedJSON(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChatTruncated(t *testing.T) {
+	truncated := mockollama.Response{Content: `{"name":"Gallus gallus","countries":["China","Ir`, DoneReason: "length"}
+	client, _ := newTestClient(t, truncated, mockollama.Response{Content: `{"name":"Gallus gallus"}`})
+	client.MaxRetries = 1
+	result, err := client.Chat(context.Background(), "granite3-moe:1b", userMessages("chicken"), nameSchema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Raw != `{"name":"Gallus gallus"}` || result.Metrics.Truncated != 1 || result.Metrics.Requests != 2 {
+		t.Errorf("result = %s, metrics = %+v", result.Raw, result.Metrics)
+	}
+
+	client, _ = newTestClient(t, truncated)
+	_, err = client.Chat(context.Background(), "granite3-moe:1b", userMessages("chicken"), nil)
+	var invalid *ValidationError
+	if !errors.As(err, &invalid) || invalid.Violations[0] != truncatedViolation {
+		t.Errorf("err = %v, want a truncation", err)
+	}
+
+	client, _ = newTestClient(t, truncated)
+	_, err = client.StreamJSONChat(context.Background(), "granite3-moe:1b", userMessages("chicken"), nameSchema(), nil)
+	if !errors.As(err, &invalid) || invalid.Violations[0] != truncatedViolation {
+		t.Errorf("stream err = %v, want a truncation", err)
+	}
+}
+
+func TestExtractCleansAnswer(t *testing.T) {
+	fenced := "```json\n{\"scientific_name\": \"Gallus gallus\", \"average_length\": 1.5, \"countries\": [\"China\",],}\n```"
+
+	client, _ := newTestClient(t, mockollama.Response{Content: fenced})
+	animal, err := Extract[testAnimal](context.Background(), client, "granite3-moe:1b", "chicken")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if animal.ScientificName != "Gallus gallus" || len(animal.Countries) != 1 {
+		t.Errorf("animal = %+v", animal)
+	}
+
+	strict, _ := newTestClient(t, mockollama.Response{Content: fenced})
+	strict.Strict = true
+	if _, err := Extract[testAnimal](context.Background(), strict, "granite3-moe:1b", "chicken"); err == nil {
+		t.Error("strict client accepted a fenced answer")
+	}
+}
+
+func TestStripThinking(t *testing.T) {
+	tests := []struct {
+		name, raw, want string
+	}{
+		{"think block", "<think>\nThe user wants {\"a\": 0}...\n</think>\n{\"a\": 1}", `{"a": 1}`},
+		{"several blocks", `<think>x</think> <thinking>y</thinking> {"a": 1}`, `{"a": 1}`},
+		{"unclosed block", `{"a": 1}<think>and now`, `{"a": 1}`},
+		{"valid answer with tags", `{"tip": "wrap your reasoning in <think> tags", "a": "<reasoning>"}`, `{"tip": "wrap your reasoning in <think> tags", "a": "<reasoning>"}`},
+		{"tags in a string", "<think>\"quoted\"</think>```json\n{\"tip\": \"<think>x</think>\",}\n```", `{"tip": "<think>x</think>"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CleanJSON(tt.raw); got != tt.want {
+				t.Errorf("CleanJSON(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFirstJSONDocument(t *testing.T) {
+	tests := []struct {
+		text, want string
+		ok         bool
+	}{
+		{`{"a": 1} {"b": 2}`, `{"a": 1}`, true},
+		{`Answer: [1, 2] then {"b": 2}`, `[1, 2]`, true},
+		{`{"a": [1, 2,]} and {"b": "}"}`, `{"b": "}"}`, true},
+		{`{"a": [1, 2]`, ``, false},
+		{`no JSON here`, ``, false},
+	}
+	for _, tt := range tests {
+		got, ok := FirstJSONDocument(tt.text)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("FirstJSONDocument(%q) = %q, %v, want %q, %v", tt.text, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+// Package structured wraps the Ollama chat API to get JSON answers from a
+// model, either with the plain "json" format or constrained by a JSON schema
+// (structured outputs).
+package structured
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+
+	"github.com/ollama/ollama/api"
+)
+
+// DefaultHost is used when OLLAMA_HOST is not set.
+const DefaultHost = "http://localhost:11434"
+
+// Client wraps an Ollama api.Client and holds the options sent with every
+// chat request.
+type Client struct {
+	api *api.Client
+
+	// Options are sent to the model with every request, DefaultOptions by
+	// default.
+	Options Options
+
+	// Explain makes Chat ask the model for a free-text rationale of its
+	// answer, in a second message. See Result.Explanation.
+	Explain bool
+
+	// Strategy used by Chat and Extract to get the answer, Direct if nil.
+	Strategy Strategy
+
+	// Strategies overrides Strategy for some schemas, by schema title
+	// (the Go type name for generated schemas).
+	Strategies map[string]Strategy
+
+	// MaxRetries is the number of new attempts made by Chat and Extract
+	// when the answer doesn't match the schema. A *ValidationError is
+	// returned once they are exhausted.
+	MaxRetries int
+
+	// Fallbacks are the models tried in order when the answers of the
+	// requested model still don't match the schema after MaxRetries, e.g.
+	// bigger models. Result.Model tells which one answered.
+	Fallbacks []string
+
+	// Voting, when set, merges several answers by majority vote, see
+	// Voting.
+	Voting *Voting
+
+	// Repair sends the invalid answer and its violations back to the model
+	// on retry, instead of asking the same question again.
+	Repair bool
+
+	// Strict disables CleanJSON: answers are validated exactly as the model
+	// produced them.
+	Strict bool
+
+	// Capabilities adapt the prompt and the strategy to the model quirks,
+	// DefaultCapabilities by default.
+	Capabilities Capabilities
+
+	// Shadow, when set, mirrors requests to a candidate model or prompt.
+	Shadow *Shadow
+
+	// Drift, when set, records the statistics of the valid answers.
+	Drift *DriftMonitor
+
+	// Cache, when set, returns the stored result of an identical request
+	// (model, messages, options, schema, strategy, strictness...) instead of asking
+	// the model again. Explanations are not cached.
+	Cache Cache
+
+	// Logger receives the logs of the client, nothing is logged when nil.
+	// The requests and responses are logged at debug level, retries and
+	// fallbacks at info level.
+	Logger *slog.Logger
+
+	mu            sync.Mutex
+	schemaSupport *bool
+}
+
+// discard is the logger of the clients without Logger.
+var discard = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func (c *Client) log() *slog.Logger {
+	if c.Logger == nil {
+		return discard
+	}
+	return c.Logger
+}
+
+// NewClient returns a Client talking to the Ollama server at rawURL.
+func NewClient(rawURL string) (*Client, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		api:          api.NewClient(u, http.DefaultClient),
+		Options:      DefaultOptions(),
+		Capabilities: DefaultCapabilities,
+	}, nil
+}
+
+// NewClientFromEnv returns a Client for the server set in OLLAMA_HOST,
+// or DefaultHost when the variable is empty.
+func NewClientFromEnv() (*Client, error) {
+	var ollamaRawUrl string
+	if ollamaRawUrl = os.Getenv("OLLAMA_HOST"); ollamaRawUrl == "" {
+		ollamaRawUrl = DefaultHost
+	}
+	return NewClient(ollamaRawUrl)
+}
+
+// API returns the underlying Ollama client.
+func (c *Client) API() *api.Client {
+	return c.api
+}
+
+// JSONChat sends messages to model and returns the JSON answer.
+// When schema is nil the model is only asked for JSON ("json" format),
+// otherwise schema is marshaled and used as the structured output format.
+func (c *Client) JSONChat(ctx context.Context, model string, messages []api.Message, schema any) (string, error) {
+	format := json.RawMessage(`"json"`)
+	if schema != nil {
+		jsonModel, err := json.Marshal(schema)
+		if err != nil {
+			return "", err
+		}
+		format = json.RawMessage(jsonModel)
+	}
+	resp, err := c.chat(ctx, &api.ChatRequest{Model: model, Messages: messages, Format: format})
+	if err != nil {
+		return "", err
+	}
+	return resp.Message.Content, nil
+}
+
+// TextChat sends messages to model and returns its free-text answer.
+func (c *Client) TextChat(ctx context.Context, model string, messages []api.Message) (string, error) {
+	resp, err := c.chat(ctx, &api.ChatRequest{Model: model, Messages: messages})
+	if err != nil {
+		return "", err
+	}
+	return resp.Message.Content, nil
+}
+
+// chat runs req without streaming, with the options of the client, and
+// returns the final response.
+func (c *Client) chat(ctx context.Context, req *api.ChatRequest) (api.ChatResponse, error) {
+	stream := false
+	req.Stream = &stream
+	req.Options = c.Options.ToMap()
+	if err := waitRateLimiter(ctx); err != nil {
+		return api.ChatResponse{}, err
+	}
+	c.logRequest(ctx, req)
+
+	var answer api.ChatResponse
+	err := c.api.Chat(ctx, req, func(resp api.ChatResponse) error {
+		answer = resp
+		return nil
+	})
+	if err != nil {
+		c.log().WarnContext(ctx, "chat request failed", "model", req.Model, "error", err)
+		return api.ChatResponse{}, err
+	}
+	c.logResponse(ctx, answer, answer.Message.Content)
+	recordUsage(ctx, answer)
+	return answer, nil
+}
+
+// logRequest logs the JSON of req at debug level.
+func (c *Client) logRequest(ctx context.Context, req *api.ChatRequest) {
+	if !c.log().Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+	body, _ := json.Marshal(req)
+	c.log().DebugContext(ctx, "chat request", "model", req.Model, "body", string(body))
+}
+
+// logResponse logs the final response of a chat, with the full content of
+// the answer, at debug level.
+func (c *Client) logResponse(ctx context.Context, resp api.ChatResponse, content string) {
+	c.log().DebugContext(ctx, "chat response",
+		"model", resp.Model,
+		"content", content,
+		"done_reason", resp.DoneReason,
+		"prompt_tokens", resp.PromptEvalCount,
+		"output_tokens", resp.EvalCount,
+		"duration", resp.TotalDuration,
+	)
+}
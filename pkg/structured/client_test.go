@@ -0,0 +1,164 @@
+package structured
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"01-json-output/internal/mockollama"
+)
+
+// newTestClient returns a client talking to a mock server answering
+// responses.
+func newTestClient(t *testing.T, responses ...mockollama.Response) (*Client, *mockollama.Server) {
+	t.Helper()
+	server := mockollama.New(responses...)
+	t.Cleanup(server.Close)
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, server
+}
+
+func TestJSONChatFormat(t *testing.T) {
+	schema := map[string]any{"type": "object"}
+	tests := []struct {
+		name   string
+		schema any
+		format string
+	}{
+		{"json mode", nil, `"json"`},
+		{"schema", schema, `{"type":"object"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, server := newTestClient(t, mockollama.Response{Content: `{"a":1}`})
+
+			answer, err := client.JSONChat(context.Background(), "granite3-moe:1b", userMessages("chicken"), tt.schema)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if answer != `{"a":1}` {
+				t.Errorf("answer = %s", answer)
+			}
+
+			req := server.Requests()[0]
+			if string(req.Format) != tt.format {
+				t.Errorf("format = %s, want %s", req.Format, tt.format)
+			}
+			if req.Model != "granite3-moe:1b" || req.Stream == nil || *req.Stream {
+				t.Errorf("model = %s, stream = %v", req.Model, req.Stream)
+			}
+			if req.Options["repeat_last_n"] != float64(2) {
+				t.Errorf("options = %v", req.Options)
+			}
+		})
+	}
+}
+
+func TestTextChat(t *testing.T) {
+	client, server := newTestClient(t, mockollama.Response{Content: "A chicken is a bird."})
+
+	answer, err := client.TextChat(context.Background(), "granite3-moe:1b", userMessages("chicken"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if answer != "A chicken is a bird." {
+		t.Errorf("answer = %s", answer)
+	}
+	if format := server.Requests()[0].Format; len(format) != 0 {
+		t.Errorf("format = %s, want none", format)
+	}
+}
+
+func TestChatServerError(t *testing.T) {
+	client, _ := newTestClient(t, mockollama.Response{Status: 404, Error: `model "nope" not found`})
+
+	_, err := client.Chat(context.Background(), "nope", userMessages("chicken"), nil)
+	if err == nil || err.Error() != `model "nope" not found` {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestChatExplain(t *testing.T) {
+	client, server := newTestClient(t,
+		mockollama.Response{Content: `{"name":"chicken"}`},
+		mockollama.Response{Content: "The user asked about chicken."},
+	)
+	client.Explain = true
+
+	result, err := client.Chat(context.Background(), "granite3-moe:1b", userMessages("chicken"), nameSchema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Raw != `{"name":"chicken"}` || result.Explanation != "The user asked about chicken." {
+		t.Errorf("result = %+v", result)
+	}
+
+	followUp := server.Requests()[1]
+	if len(followUp.Format) != 0 {
+		t.Errorf("explanation format = %s, want none", followUp.Format)
+	}
+	if n := len(followUp.Messages); n != 3 || followUp.Messages[1].Content != result.Raw {
+		t.Errorf("explanation messages = %+v", followUp.Messages)
+	}
+}
+
+func TestNewClientFromEnv(t *testing.T) {
+	t.Setenv("OLLAMA_HOST", "")
+	if _, err := NewClientFromEnv(); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("OLLAMA_HOST", "http://[::1")
+	if _, err := NewClientFromEnv(); err == nil {
+		t.Error("expected an error for an invalid OLLAMA_HOST")
+	}
+}
+
+// nameSchema is a minimal schema with one required string.
+func nameSchema() map[string]any {
+	var schema map[string]any
+	json.Unmarshal([]byte(`{
+		"type": "object",
+		"properties": {"name": {"type": "string"}},
+		"required": ["name"]
+	}`), &schema)
+	return schema
+}
+
+func TestLogger(t *testing.T) {
+	client, _ := newTestClient(t,
+		mockollama.Response{Content: `{}`},
+		mockollama.Response{Content: `{"name":"chicken"}`},
+	)
+	client.MaxRetries = 1
+
+	var logs bytes.Buffer
+	client.Logger = slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	if _, err := client.Chat(context.Background(), "granite3-moe:1b", userMessages("chicken"), nameSchema()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		`msg="chat request" model=granite3-moe:1b body=`,
+		`msg="invalid answer" model=granite3-moe:1b attempt=1 attempts=2 violations=1`,
+		`msg="chat response" model=granite3-moe:1b content="{\"name\":\"chicken\"}"`,
+	} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("logs don't contain %s:\n%s", want, logs.String())
+		}
+	}
+
+	// info level: no requests nor responses
+	logs.Reset()
+	client.Logger = slog.New(slog.NewTextHandler(&logs, nil))
+	client.Chat(context.Background(), "granite3-moe:1b", userMessages("chicken"), nameSchema())
+	if strings.Contains(logs.String(), "chat request") {
+		t.Errorf("debug logs at info level:\n%s", logs.String())
+	}
+}
@@ -0,0 +1,95 @@
+package structured
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+// Comparison holds the answers of several models to the same request, see
+// Client.Compare.
+type Comparison struct {
+	// Answers are in the order of the models.
+	Answers []ModelAnswer
+	// Fields are the top-level fields of the valid answers, sorted, and
+	// Differences the ones whose value is not the same in all of them.
+	Fields      []string
+	Differences []string
+}
+
+// ModelAnswer is the answer of one model of a Comparison.
+type ModelAnswer struct {
+	Model string
+	// Result is nil when Err is set; a *ValidationError means the model
+	// answered but never matched the schema.
+	Result *Result
+	Err    error
+
+	// Metrics count the requests made for the answer, valid or not.
+	Metrics Metrics
+
+	// Values are the top-level fields of the answer.
+	Values map[string]any
+}
+
+// Compare sends the same messages and schema to the models concurrently,
+// with the settings of the client (strategy, retries...) but no fallback,
+// shadow, drift monitoring or explanation, and compares their answers field
+// by field.
+func (c *Client) Compare(ctx context.Context, models []string, messages []api.Message, schema any) *Comparison {
+	client := c.clone()
+	client.Fallbacks = nil
+	client.Shadow = nil
+	client.Drift = nil
+
+	comparison := &Comparison{Answers: make([]ModelAnswer, len(models))}
+	var wg sync.WaitGroup
+	for i, model := range models {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, u := withUsage(ctx)
+			start := time.Now()
+			result, err := client.run(ctx, model, messages, schema)
+			answer := ModelAnswer{Model: model, Result: result, Err: err, Metrics: u.Metrics()}
+			answer.Metrics.WallTime = time.Since(start)
+			if err == nil {
+				json.Unmarshal([]byte(result.Raw), &answer.Values)
+			}
+			comparison.Answers[i] = answer
+		}()
+	}
+	wg.Wait()
+
+	comparison.compare()
+	return comparison
+}
+
+// compare lists the fields and the differences of the valid answers.
+func (c *Comparison) compare() {
+	var valid []map[string]any
+	names := map[string]bool{}
+	for _, answer := range c.Answers {
+		if answer.Err != nil {
+			continue
+		}
+		valid = append(valid, answer.Values)
+		for name := range answer.Values {
+			names[name] = true
+		}
+	}
+	c.Fields = sortedKeys(names)
+
+	for _, name := range c.Fields {
+		for _, values := range valid[1:] {
+			if !reflect.DeepEqual(valid[0][name], values[name]) {
+				c.Differences = append(c.Differences, name)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package structured
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"01-json-output/internal/mockollama"
+)
+
+func TestCompare(t *testing.T) {
+	client, server := newTestClient(t)
+	server.PushModel("granite3-moe:1b", mockollama.Response{Content: `{"name":"chicken","legs":2}`, PromptEvalCount: 10, EvalCount: 8})
+	server.PushModel("llama3.2:3b", mockollama.Response{Content: `{"name":"chicken","legs":4}`, PromptEvalCount: 12, EvalCount: 9})
+	server.PushModel("qwen2.5:7b", mockollama.Response{Content: `{"legs":2}`})
+	client.Fallbacks = []string{"llama3.2:3b"}
+
+	models := []string{"granite3-moe:1b", "llama3.2:3b", "qwen2.5:7b"}
+	comparison := client.Compare(context.Background(), models, userMessages("chicken"), nameSchema())
+
+	if len(comparison.Answers) != 3 {
+		t.Fatalf("%d answers", len(comparison.Answers))
+	}
+	for i, answer := range comparison.Answers {
+		if answer.Model != models[i] {
+			t.Errorf("answer %d of %s, want %s", i, answer.Model, models[i])
+		}
+	}
+	granite := comparison.Answers[0]
+	if granite.Err != nil || granite.Metrics.PromptTokens != 10 || granite.Metrics.OutputTokens != 8 || granite.Metrics.WallTime <= 0 {
+		t.Errorf("granite answer = %+v", granite)
+	}
+	var invalid *ValidationError
+	if !errors.As(comparison.Answers[2].Err, &invalid) {
+		t.Errorf("qwen err = %v, want a *ValidationError", comparison.Answers[2].Err)
+	}
+	if !reflect.DeepEqual(comparison.Fields, []string{"legs", "name"}) || !reflect.DeepEqual(comparison.Differences, []string{"legs"}) {
+		t.Errorf("fields = %v, differences = %v", comparison.Fields, comparison.Differences)
+	}
+	// no fallback: qwen is asked once
+	if n := len(server.Requests()); n != 3 {
+		t.Errorf("%d requests, want 3", n)
+	}
+}
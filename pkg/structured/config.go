@@ -0,0 +1,165 @@
+package structured
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/ollama/ollama/api"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultModel is the model of the examples and of jsonout when the
+// configuration doesn't set one.
+const DefaultModel = "granite3-moe:1b"
+
+// ConfigFiles are the files looked for in the working directory by
+// LoadConfigFromEnv when STRUCTURED_CONFIG is not set.
+var ConfigFiles = []string{"config.yaml", "config.yml", "config.toml"}
+
+// Config holds the settings shared by the examples and jsonout, read from a
+// YAML or TOML file and overridden by environment variables, see
+// LoadConfig.
+type Config struct {
+	// Host is the Ollama server, DefaultHost when empty.
+	Host string `yaml:"host" toml:"host"`
+	// Model is the default model, DefaultModel when empty.
+	Model string `yaml:"model" toml:"model"`
+	// Temperature replaces the one of DefaultOptions when set.
+	Temperature *float64 `yaml:"temperature" toml:"temperature"`
+	// Retries is Client.MaxRetries.
+	Retries int `yaml:"retries" toml:"retries"`
+	// Repair is Client.Repair.
+	Repair bool `yaml:"repair" toml:"repair"`
+	// Timeout limits each HTTP request to the server, none when 0. It is
+	// written as a Go duration: "30s", "2m"...
+	Timeout Duration `yaml:"timeout" toml:"timeout"`
+}
+
+// Duration is a time.Duration read from a string such as "1m30s".
+type Duration time.Duration
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+	return d.UnmarshalText([]byte(value.Value))
+}
+
+// DefaultConfig returns the settings used without configuration file.
+func DefaultConfig() Config {
+	return Config{Host: DefaultHost, Model: DefaultModel}
+}
+
+// LoadConfig reads the configuration from a .yaml, .yml or .toml file,
+// then applies the environment variables: OLLAMA_HOST, STRUCTURED_MODEL,
+// STRUCTURED_TEMPERATURE, STRUCTURED_RETRIES and STRUCTURED_TIMEOUT. The
+// settings absent from both keep their DefaultConfig value.
+func LoadConfig(path string) (Config, error) {
+	config := DefaultConfig()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &config)
+	case ".toml":
+		err = toml.Unmarshal(data, &config)
+	default:
+		return config, fmt.Errorf("structured: %s: unknown configuration format, expected .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return config, fmt.Errorf("structured: %s: %w", path, err)
+	}
+	if err := config.applyEnv(); err != nil {
+		return config, err
+	}
+	return config, nil
+}
+
+// LoadConfigFromEnv loads the file set in STRUCTURED_CONFIG or else the
+// first of ConfigFiles found in the working directory. Without file, the
+// DefaultConfig is returned with the environment variables applied.
+func LoadConfigFromEnv() (Config, error) {
+	if path := os.Getenv("STRUCTURED_CONFIG"); path != "" {
+		return LoadConfig(path)
+	}
+	for _, path := range ConfigFiles {
+		config, err := LoadConfig(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		return config, err
+	}
+	config := DefaultConfig()
+	return config, config.applyEnv()
+}
+
+// applyEnv overrides the settings set in the environment.
+func (c *Config) applyEnv() error {
+	if v := os.Getenv("OLLAMA_HOST"); v != "" {
+		c.Host = v
+	}
+	if v := os.Getenv("STRUCTURED_MODEL"); v != "" {
+		c.Model = v
+	}
+	if v := os.Getenv("STRUCTURED_TEMPERATURE"); v != "" {
+		temperature, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("structured: STRUCTURED_TEMPERATURE: %w", err)
+		}
+		c.Temperature = Float(temperature)
+	}
+	if v := os.Getenv("STRUCTURED_RETRIES"); v != "" {
+		retries, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("structured: STRUCTURED_RETRIES: %w", err)
+		}
+		c.Retries = retries
+	}
+	if v := os.Getenv("STRUCTURED_TIMEOUT"); v != "" {
+		timeout, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("structured: STRUCTURED_TIMEOUT: %w", err)
+		}
+		c.Timeout = Duration(timeout)
+	}
+	return nil
+}
+
+// NewClient returns a Client for c.Host with the settings of c.
+func (c Config) NewClient() (*Client, error) {
+	host := c.Host
+	if host == "" {
+		host = DefaultHost
+	}
+	client, err := NewClient(host)
+	if err != nil {
+		return nil, err
+	}
+	if c.Timeout > 0 {
+		u, _ := url.Parse(host)
+		client.api = api.NewClient(u, &http.Client{Timeout: time.Duration(c.Timeout)})
+	}
+	if c.Temperature != nil {
+		client.Options.Temperature = c.Temperature
+	}
+	client.MaxRetries = c.Retries
+	client.Repair = c.Repair
+	return client, nil
+}
@@ -0,0 +1,102 @@
+package structured
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// clearConfigEnv unsets the variables read by LoadConfig for the test.
+func clearConfigEnv(t *testing.T) {
+	for _, name := range []string{"STRUCTURED_CONFIG", "OLLAMA_HOST", "STRUCTURED_MODEL", "STRUCTURED_TEMPERATURE", "STRUCTURED_RETRIES", "STRUCTURED_TIMEOUT"} {
+		t.Setenv(name, "")
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	clearConfigEnv(t)
+	files := map[string]string{
+		"config.yaml": "host: http://ollama:11434\nmodel: qwen2.5:7b\ntemperature: 0.2\nretries: 2\nrepair: true\ntimeout: 1m30s\n",
+		"config.toml": "host = \"http://ollama:11434\"\nmodel = \"qwen2.5:7b\"\ntemperature = 0.2\nretries = 2\nrepair = true\ntimeout = \"1m30s\"\n",
+	}
+	for name, content := range files {
+		config, err := LoadConfig(writeFile(t, name, content))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if config.Host != "http://ollama:11434" || config.Model != "qwen2.5:7b" || config.Retries != 2 || !config.Repair {
+			t.Errorf("%s: config = %+v", name, config)
+		}
+		if config.Temperature == nil || *config.Temperature != 0.2 {
+			t.Errorf("%s: temperature = %v", name, config.Temperature)
+		}
+		if time.Duration(config.Timeout) != 90*time.Second {
+			t.Errorf("%s: timeout = %v", name, time.Duration(config.Timeout))
+		}
+	}
+}
+
+func TestLoadConfigDefaults(t *testing.T) {
+	clearConfigEnv(t)
+	config, err := LoadConfig(writeFile(t, "config.yaml", "retries: 1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Host != DefaultHost || config.Model != DefaultModel || config.Temperature != nil {
+		t.Errorf("config = %+v", config)
+	}
+}
+
+func TestLoadConfigEnv(t *testing.T) {
+	clearConfigEnv(t)
+	t.Setenv("OLLAMA_HOST", "http://gpu:11434")
+	t.Setenv("STRUCTURED_MODEL", "llama3.2:3b")
+	t.Setenv("STRUCTURED_RETRIES", "3")
+	t.Setenv("STRUCTURED_TIMEOUT", "10s")
+	t.Setenv("STRUCTURED_CONFIG", writeFile(t, "config.yaml", "host: http://ollama:11434\nmodel: qwen2.5:7b\nretries: 2\n"))
+
+	config, err := LoadConfigFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Host != "http://gpu:11434" || config.Model != "llama3.2:3b" || config.Retries != 3 || time.Duration(config.Timeout) != 10*time.Second {
+		t.Errorf("config = %+v", config)
+	}
+
+	t.Setenv("STRUCTURED_RETRIES", "many")
+	if _, err := LoadConfigFromEnv(); err == nil || !strings.Contains(err.Error(), "STRUCTURED_RETRIES") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	clearConfigEnv(t)
+	tests := []struct {
+		name, content, want string
+	}{
+		{"config.json", `{}`, "unknown configuration format"},
+		{"config.yaml", "timeout: soon\n", "invalid duration"},
+		{"config.toml", "retries = \"two\"\n", "config.toml"},
+	}
+	for _, test := range tests {
+		_, err := LoadConfig(writeFile(t, test.name, test.content))
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: err = %v, want %q", test.name, err, test.want)
+		}
+	}
+}
+
+func TestConfigNewClient(t *testing.T) {
+	config := DefaultConfig()
+	config.Temperature = Float(0.5)
+	config.Retries = 2
+	config.Repair = true
+	config.Timeout = Duration(time.Minute)
+	client, err := config.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *client.Options.Temperature != 0.5 || client.MaxRetries != 2 || !client.Repair {
+		t.Errorf("client = %+v", client)
+	}
+}
@@ -0,0 +1,220 @@
+package structured
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// SchemaChange is a difference between two versions of a schema.
+type SchemaChange struct {
+	// Path is the property, "" for the root and "[]" for array items.
+	Path    string
+	Message string
+	// Breaking is set when consumers of the answers, or the answers stored
+	// with the old schema, can be broken by the change.
+	Breaking bool
+}
+
+func (c SchemaChange) String() string {
+	s := c.Message
+	if c.Path != "" {
+		s = c.Path + ": " + s
+	}
+	if c.Breaking {
+		s += " (breaking)"
+	}
+	return s
+}
+
+// DiffSchemas lists the changes between two versions of a schema, from the
+// previous one to the edited one. The breaking ones are removed properties,
+// type changes, required properties becoming optional or the other way
+// round, and tightened constraints (enum values removed, minimum raised,
+// new pattern...). Added optional properties and loosened constraints are
+// not breaking.
+func DiffSchemas(from, to any) ([]SchemaChange, error) {
+	oldMap, err := normalizeSchema(from)
+	if err != nil {
+		return nil, err
+	}
+	newMap, err := normalizeSchema(to)
+	if err != nil {
+		return nil, err
+	}
+	var changes []SchemaChange
+	diffSchema(oldMap, newMap, "", &changes)
+	return changes, nil
+}
+
+// BreakingChanges returns the breaking changes of changes.
+func BreakingChanges(changes []SchemaChange) []SchemaChange {
+	var breaking []SchemaChange
+	for _, change := range changes {
+		if change.Breaking {
+			breaking = append(breaking, change)
+		}
+	}
+	return breaking
+}
+
+// normalizeSchema converts schema to decoded JSON, so that both versions
+// have the same Go types ([]any, float64...).
+func normalizeSchema(schema any) (map[string]any, error) {
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("structured: schema is not a JSON object: %w", err)
+	}
+	return m, nil
+}
+
+func diffSchema(from, to map[string]any, path string, changes *[]SchemaChange) {
+	report := func(breaking bool, format string, args ...any) {
+		*changes = append(*changes, SchemaChange{Path: path, Message: fmt.Sprintf(format, args...), Breaking: breaking})
+	}
+
+	oldTypes, newTypes := schemaTypes(from["type"]), schemaTypes(to["type"])
+	if !slices.Equal(sortedCopy(oldTypes), sortedCopy(newTypes)) {
+		report(true, "type changed from %s to %s", typeList(oldTypes), typeList(newTypes))
+		// the other keywords of different types can't be compared
+		return
+	}
+
+	diffEnum(from["enum"], to["enum"], report)
+	diffBound(from, to, "minimum", true, report)
+	diffBound(from, to, "maximum", false, report)
+	diffBound(from, to, "minLength", true, report)
+	diffBound(from, to, "maxLength", false, report)
+	for _, keyword := range []string{"pattern", "format"} {
+		oldValue, hadIt := from[keyword].(string)
+		newValue, hasIt := to[keyword].(string)
+		switch {
+		case !hadIt && hasIt:
+			report(true, "%s %q added", keyword, newValue)
+		case hadIt && !hasIt:
+			report(false, "%s %q removed", keyword, oldValue)
+		case oldValue != newValue:
+			report(true, "%s changed from %q to %q", keyword, oldValue, newValue)
+		}
+	}
+
+	oldProperties, _ := from["properties"].(map[string]any)
+	newProperties, _ := to["properties"].(map[string]any)
+	oldRequired, newRequired := toStrings(from["required"]), toStrings(to["required"])
+	names := map[string]bool{}
+	for name := range oldProperties {
+		names[name] = true
+	}
+	for name := range newProperties {
+		names[name] = true
+	}
+	for _, name := range sortedKeys(names) {
+		propertyPath := joinPath(path, name)
+		oldProperty, hadIt := oldProperties[name].(map[string]any)
+		newProperty, hasIt := newProperties[name].(map[string]any)
+		wasRequired, isRequired := slices.Contains(oldRequired, name), slices.Contains(newRequired, name)
+		switch {
+		case !hasIt:
+			*changes = append(*changes, SchemaChange{Path: propertyPath, Message: "property removed", Breaking: true})
+		case !hadIt && isRequired:
+			*changes = append(*changes, SchemaChange{Path: propertyPath, Message: "required property added", Breaking: true})
+		case !hadIt:
+			*changes = append(*changes, SchemaChange{Path: propertyPath, Message: "optional property added"})
+		default:
+			if wasRequired != isRequired {
+				message := "property now required"
+				if wasRequired {
+					message = "property now optional"
+				}
+				*changes = append(*changes, SchemaChange{Path: propertyPath, Message: message, Breaking: true})
+			}
+			diffSchema(oldProperty, newProperty, propertyPath, changes)
+		}
+	}
+
+	oldItems, _ := from["items"].(map[string]any)
+	newItems, _ := to["items"].(map[string]any)
+	if oldItems != nil && newItems != nil {
+		diffSchema(oldItems, newItems, path+"[]", changes)
+	}
+}
+
+// diffEnum compares the values of enum, removed values being breaking.
+func diffEnum(from, to any, report func(bool, string, ...any)) {
+	oldValues, newValues := enumValuesJSON(from), enumValuesJSON(to)
+	switch {
+	case oldValues == nil && newValues == nil:
+	case oldValues == nil:
+		report(true, "enum %s added", strings.Join(newValues, ", "))
+	case newValues == nil:
+		report(false, "enum removed")
+	default:
+		var removed, added []string
+		for _, v := range oldValues {
+			if !slices.Contains(newValues, v) {
+				removed = append(removed, v)
+			}
+		}
+		for _, v := range newValues {
+			if !slices.Contains(oldValues, v) {
+				added = append(added, v)
+			}
+		}
+		if len(removed) > 0 {
+			report(true, "enum values removed: %s", strings.Join(removed, ", "))
+		}
+		if len(added) > 0 {
+			report(false, "enum values added: %s", strings.Join(added, ", "))
+		}
+	}
+}
+
+// enumValuesJSON returns the values of enum as JSON, nil without enum.
+func enumValuesJSON(enum any) []string {
+	values, ok := enum.([]any)
+	if !ok {
+		return nil
+	}
+	strs := make([]string, len(values))
+	for i, v := range values {
+		strs[i] = jsonString(v)
+	}
+	return strs
+}
+
+// diffBound compares a numeric bound, lower is true for the lower bounds
+// (raising them tightens the schema).
+func diffBound(from, to map[string]any, keyword string, lower bool, report func(bool, string, ...any)) {
+	oldValue, hadIt := toFloat(from[keyword])
+	newValue, hasIt := toFloat(to[keyword])
+	switch {
+	case !hadIt && hasIt:
+		report(true, "%s %v added", keyword, newValue)
+	case hadIt && !hasIt:
+		report(false, "%s %v removed", keyword, oldValue)
+	case oldValue != newValue:
+		tightened := newValue > oldValue
+		if !lower {
+			tightened = !tightened
+		}
+		report(tightened, "%s changed from %v to %v", keyword, oldValue, newValue)
+	}
+}
+
+func sortedCopy(s []string) []string {
+	s = slices.Clone(s)
+	slices.Sort(s)
+	return s
+}
+
+func typeList(types []string) string {
+	if len(types) == 0 {
+		return "any"
+	}
+	return strings.Join(types, "|")
+}
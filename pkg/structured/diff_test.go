@@ -0,0 +1,80 @@
+package structured
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestDiffSchemas(t *testing.T) {
+	old := `{
+		"type": "object",
+		"properties": {
+			"name": {"type": "string"},
+			"diet": {"type": "string", "enum": ["herbivore", "carnivore", "omnivore"]},
+			"length": {"type": "number", "minimum": 0},
+			"legs": {"type": "integer"},
+			"countries": {"type": "array", "items": {"type": "string", "maxLength": 20}},
+			"notes": {"type": "string"}
+		},
+		"required": ["name", "diet", "length", "notes"]
+	}`
+	new := `{
+		"type": "object",
+		"properties": {
+			"name": {"type": "string", "description": "common name"},
+			"diet": {"type": "string", "enum": ["herbivore", "carnivore", "insectivore"]},
+			"length": {"type": "number", "minimum": 0.1},
+			"legs": {"type": "number"},
+			"countries": {"type": "array", "items": {"type": "string", "maxLength": 60}},
+			"habitat": {"type": "string"},
+			"weight": {"type": "number"}
+		},
+		"required": ["name", "diet", "length", "weight"]
+	}`
+
+	changes, err := DiffSchemas(decode(t, old), decode(t, new))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, change := range changes {
+		got = append(got, change.String())
+	}
+	want := []string{
+		"countries[]: maxLength changed from 20 to 60",
+		`diet: enum values removed: "omnivore" (breaking)`,
+		`diet: enum values added: "insectivore"`,
+		"habitat: optional property added",
+		"legs: type changed from integer to number (breaking)",
+		"length: minimum changed from 0 to 0.1 (breaking)",
+		"notes: property removed (breaking)",
+		"weight: required property added (breaking)",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("changes =\n%q\nwant\n%q", got, want)
+	}
+	if n := len(BreakingChanges(changes)); n != 5 {
+		t.Errorf("%d breaking changes, want 5", n)
+	}
+}
+
+func TestDiffSchemasSame(t *testing.T) {
+	schema, err := SchemaOf[testAnimal]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes, err := DiffSchemas(schema, schema)
+	if err != nil || len(changes) != 0 {
+		t.Errorf("changes = %v, err = %v", changes, err)
+	}
+}
+
+func decode(t *testing.T, schema string) map[string]any {
+	t.Helper()
+	var m map[string]any
+	if err := json.Unmarshal([]byte(schema), &m); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
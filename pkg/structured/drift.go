@@ -0,0 +1,361 @@
+package structured
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MaxCategories is the number of distinct values above which the values of
+// a string field are not counted anymore (the field is not an enum).
+var MaxCategories = 20
+
+// FieldStats are the statistics of one top-level field of a schema.
+type FieldStats struct {
+	Count int `json:"count"`
+	Nulls int `json:"nulls"`
+
+	Numbers int     `json:"numbers,omitempty"`
+	Sum     float64 `json:"sum,omitempty"`
+	SumSq   float64 `json:"sum_sq,omitempty"`
+
+	// Categorical counts the string and boolean values, and Values counts
+	// them by value, nil once there are more than MaxCategories of them.
+	Categorical int            `json:"categorical,omitempty"`
+	Values      map[string]int `json:"values,omitempty"`
+}
+
+// hasCategories reports whether the field had string or boolean values
+// (baselines saved before Categorical only have Values).
+func (f *FieldStats) hasCategories() bool {
+	return f.Categorical > 0 || f.Values != nil
+}
+
+// NullRate is the share of answers where the field is missing or null.
+func (f *FieldStats) NullRate() float64 {
+	if f.Count == 0 {
+		return 0
+	}
+	return float64(f.Nulls) / float64(f.Count)
+}
+
+// Mean and StdDev describe the numeric values of the field.
+func (f *FieldStats) Mean() float64 {
+	if f.Numbers == 0 {
+		return 0
+	}
+	return f.Sum / float64(f.Numbers)
+}
+
+func (f *FieldStats) StdDev() float64 {
+	if f.Numbers == 0 {
+		return 0
+	}
+	mean := f.Mean()
+	return math.Sqrt(math.Max(0, f.SumSq/float64(f.Numbers)-mean*mean))
+}
+
+func (f *FieldStats) add(value any, present bool) {
+	f.Count++
+	if !present || value == nil {
+		f.Nulls++
+		return
+	}
+	switch v := value.(type) {
+	case float64:
+		f.Numbers++
+		f.Sum += v
+		f.SumSq += v * v
+	case string, bool:
+		f.Categorical++
+		if f.Categorical == 1 {
+			f.Values = map[string]int{}
+		}
+		if f.Values != nil {
+			f.Values[fmt.Sprint(v)]++
+			if len(f.Values) > MaxCategories {
+				f.Values = nil
+			}
+		}
+	}
+}
+
+func (f *FieldStats) merge(other *FieldStats) {
+	hadCategories := f.hasCategories()
+	f.Count += other.Count
+	f.Nulls += other.Nulls
+	f.Numbers += other.Numbers
+	f.Sum += other.Sum
+	f.SumSq += other.SumSq
+	f.Categorical += other.Categorical
+	switch {
+	case !other.hasCategories():
+		// nothing to count, the table of f is kept
+	case !hadCategories:
+		f.Values = maps.Clone(other.Values)
+	case f.Values != nil && other.Values != nil:
+		for v, n := range other.Values {
+			f.Values[v] += n
+		}
+		if len(f.Values) > MaxCategories {
+			f.Values = nil
+		}
+	default:
+		f.Values = nil
+	}
+}
+
+// SchemaStats are the statistics of the answers for one schema.
+type SchemaStats struct {
+	Answers int                    `json:"answers"`
+	Fields  map[string]*FieldStats `json:"fields"`
+}
+
+// DriftAlert reports a field whose statistics drifted from the baseline.
+type DriftAlert struct {
+	Schema  string `json:"schema"`
+	Field   string `json:"field"`
+	Kind    string `json:"kind"` // "null_rate", "mean" or "frequencies"
+	Message string `json:"message"`
+}
+
+// DriftMonitor tracks per-schema statistics of the answers (null rates,
+// numeric distributions, value frequencies) and compares the current batch
+// with a baseline built from the previous runs, to catch silent model or
+// prompt regressions.
+//
+// Set it as Client.Drift, or call Observe yourself; then call Check at the
+// end of a batch and Commit to add the batch to the baseline.
+type DriftMonitor struct {
+	Baseline map[string]*SchemaStats `json:"baseline"`
+
+	// MinAnswers is the number of answers needed, in the baseline and in
+	// the batch, to compare a schema (10 when 0).
+	MinAnswers int `json:"-"`
+	// NullRateDelta is the accepted change of null rate (0.2 when 0).
+	NullRateDelta float64 `json:"-"`
+	// MeanShift is the accepted change of mean, in standard deviations of
+	// the baseline (3 when 0).
+	MeanShift float64 `json:"-"`
+	// FrequencyDelta is the accepted total variation distance between the
+	// value frequencies (0.3 when 0).
+	FrequencyDelta float64 `json:"-"`
+
+	// Alert is called by Check for each drift found.
+	Alert func(DriftAlert) `json:"-"`
+
+	mu      sync.Mutex
+	current map[string]*SchemaStats
+}
+
+// LoadDriftMonitor reads a baseline saved by Save. A missing file gives an
+// empty baseline.
+func LoadDriftMonitor(path string) (*DriftMonitor, error) {
+	m := &DriftMonitor{Baseline: map[string]*SchemaStats{}}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("structured: %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// Save writes the baseline to path.
+func (m *DriftMonitor) Save(path string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// Observe adds a valid answer for schema (its title) to the current batch.
+func (m *DriftMonitor) Observe(schema string, raw string) {
+	var object map[string]any
+	if err := json.Unmarshal([]byte(raw), &object); err != nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.current == nil {
+		m.current = map[string]*SchemaStats{}
+	}
+	stats, ok := m.current[schema]
+	if !ok {
+		stats = &SchemaStats{Fields: map[string]*FieldStats{}}
+		m.current[schema] = stats
+	}
+	stats.Answers++
+
+	// fields seen before but missing from this answer count as nulls
+	for name := range object {
+		if _, ok := stats.Fields[name]; !ok {
+			stats.Fields[name] = &FieldStats{Count: stats.Answers - 1, Nulls: stats.Answers - 1}
+		}
+	}
+	for name, field := range stats.Fields {
+		value, present := object[name]
+		field.add(value, present)
+	}
+}
+
+// Check compares the current batch with the baseline, calls Alert for each
+// drift and returns them. Alert is called without holding the monitor, so a
+// slow alert doesn't block Observe.
+func (m *DriftMonitor) Check() []DriftAlert {
+	alerts := m.drifts()
+	if m.Alert != nil {
+		for _, a := range alerts {
+			m.Alert(a)
+		}
+	}
+	return alerts
+}
+
+// drifts lists the drifts of the current batch from the baseline.
+func (m *DriftMonitor) drifts() []DriftAlert {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	minAnswers := defaultInt(m.MinAnswers, 10)
+	nullRateDelta := defaultFloat(m.NullRateDelta, 0.2)
+	meanShift := defaultFloat(m.MeanShift, 3)
+	frequencyDelta := defaultFloat(m.FrequencyDelta, 0.3)
+
+	var alerts []DriftAlert
+	for _, schema := range sortedKeys(m.current) {
+		current := m.current[schema]
+		baseline, ok := m.Baseline[schema]
+		if !ok || baseline.Answers < minAnswers || current.Answers < minAnswers {
+			continue
+		}
+		for _, name := range sortedKeys(baseline.Fields) {
+			base := baseline.Fields[name]
+			cur, ok := current.Fields[name]
+			if !ok {
+				// never answered in this batch
+				cur = &FieldStats{Count: current.Answers, Nulls: current.Answers}
+			}
+			alert := func(kind, format string, args ...any) {
+				alerts = append(alerts, DriftAlert{Schema: schema, Field: name, Kind: kind, Message: fmt.Sprintf(format, args...)})
+			}
+
+			if d := math.Abs(cur.NullRate() - base.NullRate()); d > nullRateDelta {
+				alert("null_rate", "null rate %.2f, was %.2f", cur.NullRate(), base.NullRate())
+			}
+			if cur.Numbers > 0 && base.Numbers > 0 {
+				if std := base.StdDev(); std > 0 && math.Abs(cur.Mean()-base.Mean()) > meanShift*std {
+					alert("mean", "mean %.4g, was %.4g (std %.4g)", cur.Mean(), base.Mean(), std)
+				}
+			}
+			if cur.Values != nil && base.Values != nil {
+				if d := variationDistance(cur.Values, base.Values); d > frequencyDelta {
+					alert("frequencies", "value frequencies moved by %.2f", d)
+				}
+			}
+		}
+	}
+	return alerts
+}
+
+// Commit adds the current batch to the baseline and starts a new batch.
+func (m *DriftMonitor) Commit() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Baseline == nil {
+		m.Baseline = map[string]*SchemaStats{}
+	}
+	for schema, current := range m.current {
+		baseline, ok := m.Baseline[schema]
+		if !ok {
+			baseline = &SchemaStats{Fields: map[string]*FieldStats{}}
+			m.Baseline[schema] = baseline
+		}
+		baseline.Answers += current.Answers
+		for name, field := range current.Fields {
+			if _, ok := baseline.Fields[name]; !ok {
+				baseline.Fields[name] = &FieldStats{}
+			}
+			baseline.Fields[name].merge(field)
+		}
+	}
+	m.current = nil
+}
+
+// WebhookTimeout limits the delivery of an alert by WebhookAlert.
+var WebhookTimeout = 10 * time.Second
+
+// WebhookAlert returns an Alert function posting each alert as JSON to url.
+// Delivery errors are ignored: drift alerts are best effort.
+func WebhookAlert(url string) func(DriftAlert) {
+	client := &http.Client{Timeout: WebhookTimeout}
+	return func(a DriftAlert) {
+		body, _ := json.Marshal(a)
+		resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+		if err == nil {
+			resp.Body.Close()
+		}
+	}
+}
+
+// variationDistance is the total variation distance between two value
+// frequency tables.
+func variationDistance(a, b map[string]int) float64 {
+	totalA, totalB := 0, 0
+	for _, n := range a {
+		totalA += n
+	}
+	for _, n := range b {
+		totalB += n
+	}
+	values := map[string]bool{}
+	for v := range a {
+		values[v] = true
+	}
+	for v := range b {
+		values[v] = true
+	}
+	distance := 0.0
+	for v := range values {
+		distance += math.Abs(float64(a[v])/float64(totalA) - float64(b[v])/float64(totalB))
+	}
+	return distance / 2
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func defaultInt(v, fallback int) int {
+	if v == 0 {
+		return fallback
+	}
+	return v
+}
+
+func defaultFloat(v, fallback float64) float64 {
+	if v == 0 {
+		return fallback
+	}
+	return v
+}
@@ -0,0 +1,160 @@
+package structured
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"01-json-output/internal/mockollama"
+)
+
+// baselineMonitor returns a monitor whose baseline holds 10 answers with
+// legs 3 to 5, the diet "herbivore" and a name.
+func baselineMonitor(t *testing.T) *DriftMonitor {
+	t.Helper()
+	m := &DriftMonitor{}
+	for i := 0; i < 10; i++ {
+		m.Observe("Animal", fmt.Sprintf(`{"legs":%d,"diet":"herbivore","name":"cow"}`, 3+i%3))
+	}
+	m.Commit()
+	return m
+}
+
+func TestDriftMonitor(t *testing.T) {
+	m := baselineMonitor(t)
+	for i := 0; i < 10; i++ {
+		m.Observe("Animal", fmt.Sprintf(`{"legs":%d,"diet":"herbivore","name":"cow"}`, 3+i%3))
+	}
+	if alerts := m.Check(); len(alerts) != 0 {
+		t.Errorf("alerts without drift = %v", alerts)
+	}
+	m.Commit()
+
+	for i := 0; i < 10; i++ {
+		m.Observe("Animal", `{"legs":40,"diet":"carnivore"}`)
+	}
+	kinds := map[string]string{}
+	for _, a := range m.Check() {
+		kinds[a.Field] = a.Kind
+	}
+	want := map[string]string{"legs": "mean", "diet": "frequencies", "name": "null_rate"}
+	if fmt.Sprint(kinds) != fmt.Sprint(want) {
+		t.Errorf("alerts = %v, want %v", kinds, want)
+	}
+}
+
+func TestDriftMonitorMinAnswers(t *testing.T) {
+	m := baselineMonitor(t)
+	for i := 0; i < 9; i++ {
+		m.Observe("Animal", `{"legs":40}`)
+	}
+	if alerts := m.Check(); len(alerts) != 0 {
+		t.Errorf("alerts on a small batch = %v", alerts)
+	}
+}
+
+func TestDriftMonitorMaxCategories(t *testing.T) {
+	m := &DriftMonitor{}
+	for i := 0; i <= MaxCategories; i++ {
+		m.Observe("Animal", fmt.Sprintf(`{"name":"animal %d"}`, i))
+	}
+	m.Commit()
+	if values := m.Baseline["Animal"].Fields["name"].Values; values != nil {
+		t.Errorf("values = %v, want nil past MaxCategories", values)
+	}
+}
+
+func TestDriftMonitorKeepsFrequencies(t *testing.T) {
+	m := &DriftMonitor{}
+	for i := 0; i < 10; i++ {
+		m.Observe("Animal", `{"diet":"herbivore"}`)
+	}
+	m.Commit()
+	// a batch without values doesn't drop the frequencies of the baseline
+	for i := 0; i < 10; i++ {
+		m.Observe("Animal", `{"diet":null}`)
+	}
+	m.Commit()
+	if values := m.Baseline["Animal"].Fields["diet"].Values; values["herbivore"] != 10 {
+		t.Fatalf("values = %v", values)
+	}
+
+	for i := 0; i < 100; i++ {
+		m.Observe("Animal", `{"diet":"carnivore"}`)
+	}
+	kinds := map[string]bool{}
+	for _, a := range m.Check() {
+		kinds[a.Kind] = true
+	}
+	if !kinds["frequencies"] {
+		t.Errorf("alerts = %v, want a frequency drift", kinds)
+	}
+}
+
+func TestDriftMonitorSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "drift.json")
+	m, err := LoadDriftMonitor(path)
+	if err != nil || len(m.Baseline) != 0 {
+		t.Fatalf("missing file: %v, %v", m.Baseline, err)
+	}
+
+	if err := baselineMonitor(t).Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if m, err = LoadDriftMonitor(path); err != nil {
+		t.Fatal(err)
+	}
+	legs := m.Baseline["Animal"].Fields["legs"]
+	if m.Baseline["Animal"].Answers != 10 || legs.Mean() != 3.9 || m.Baseline["Animal"].Fields["diet"].Values["herbivore"] != 10 {
+		t.Errorf("baseline = %+v, legs = %+v", m.Baseline["Animal"], legs)
+	}
+}
+
+func TestDriftMonitorAlert(t *testing.T) {
+	m := baselineMonitor(t)
+	var alerts []DriftAlert
+	m.Alert = func(a DriftAlert) {
+		// the monitor is not locked during the alert
+		m.Observe("Animal", `{"legs":4}`)
+		alerts = append(alerts, a)
+	}
+	for i := 0; i < 10; i++ {
+		m.Observe("Animal", `{"legs":40,"diet":"herbivore","name":"cow"}`)
+	}
+	if returned := m.Check(); len(alerts) != 1 || len(returned) != 1 || alerts[0].Kind != "mean" {
+		t.Errorf("alerts = %v, returned %v", alerts, returned)
+	}
+}
+
+func TestWebhookAlert(t *testing.T) {
+	received := make(chan DriftAlert, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var a DriftAlert
+		json.NewDecoder(r.Body).Decode(&a)
+		received <- a
+	}))
+	defer server.Close()
+
+	WebhookAlert(server.URL)(DriftAlert{Schema: "Animal", Field: "legs", Kind: "mean"})
+	if a := <-received; a.Field != "legs" || a.Kind != "mean" {
+		t.Errorf("alert = %+v", a)
+	}
+}
+
+func TestClientDrift(t *testing.T) {
+	client, _ := newTestClient(t, mockollama.Response{Content: `{"name":"cow"}`})
+	client.Drift = &DriftMonitor{}
+	schema := nameSchema()
+	schema["title"] = "Animal"
+	if _, err := client.Chat(context.Background(), "tiny", userMessages("cow"), schema); err != nil {
+		t.Fatal(err)
+	}
+	client.Drift.Commit()
+	if stats := client.Drift.Baseline["Animal"]; stats == nil || stats.Fields["name"].Values["cow"] != 1 {
+		t.Errorf("baseline = %+v", client.Drift.Baseline)
+	}
+}
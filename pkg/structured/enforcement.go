@@ -0,0 +1,89 @@
+package structured
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+// Enforcement tells how strictly the answer format was enforced by the
+// server. The answer is always validated client-side afterwards.
+type Enforcement string
+
+const (
+	// EnforceSchema: the generation was constrained by the JSON schema
+	// (Ollama turns it into a grammar).
+	EnforceSchema Enforcement = "schema"
+	// EnforceJSON: the generation was only constrained to valid JSON, the
+	// schema was given in the prompt.
+	EnforceJSON Enforcement = "json"
+	// EnforceTool: the answer comes from the arguments of a tool call.
+	EnforceTool Enforcement = "tool"
+)
+
+func (Direct) Enforcement() Enforcement   { return EnforceSchema }
+func (Prompted) Enforcement() Enforcement { return EnforceJSON }
+func (TwoPass) Enforcement() Enforcement  { return EnforceSchema }
+func (ToolFill) Enforcement() Enforcement { return EnforceTool }
+func (PerField) Enforcement() Enforcement { return EnforceSchema }
+
+// enforcementOf returns the enforcement level of s, or "" when the strategy
+// doesn't tell.
+func enforcementOf(s Strategy) Enforcement {
+	if e, ok := s.(interface{ Enforcement() Enforcement }); ok {
+		return e.Enforcement()
+	}
+	return ""
+}
+
+// SchemaOutputsVersion is the first Ollama version supporting JSON schemas
+// in the format field (structured outputs).
+var SchemaOutputsVersion = [3]int{0, 5, 0}
+
+// SupportsSchemas tells whether the server constrains the generation with
+// JSON schemas natively. The answer is asked once and then cached; servers
+// reporting an unknown version (like development builds) are assumed to
+// support them.
+func (c *Client) SupportsSchemas(ctx context.Context) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.schemaSupport != nil {
+		return *c.schemaSupport, nil
+	}
+	version, err := c.api.Version(ctx)
+	if err != nil {
+		return false, err
+	}
+	supported := versionAtLeast(version, SchemaOutputsVersion)
+	c.schemaSupport = &supported
+	return supported, nil
+}
+
+// versionAtLeast compares a "0.5.1" like version to minimum.
+func versionAtLeast(version string, minimum [3]int) bool {
+	version = strings.TrimPrefix(version, "v")
+	version, _, _ = strings.Cut(version, "-")
+	parts := strings.Split(version, ".")
+	if len(parts) < 2 {
+		return true
+	}
+	current := [3]int{}
+	for i := 0; i < len(parts) && i < 3; i++ {
+		n, err := strconv.Atoi(parts[i])
+		if err != nil {
+			return true
+		}
+		current[i] = n
+	}
+	if current == [3]int{} {
+		// development build
+		return true
+	}
+	for i := range current {
+		if current[i] != minimum[i] {
+			return current[i] > minimum[i]
+		}
+	}
+	return true
+}
@@ -0,0 +1,61 @@
+package structured
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ollama/ollama/api"
+)
+
+// Extract asks model to answer prompt with the JSON schema of T and
+// returns the decoded answer. Like Chat, the answer is validated against
+// the schema and a *ValidationError is returned if it never matches.
+//
+//	animal, err := structured.Extract[Animal](ctx, client, "granite3-moe:1b", "Tell me about chicken")
+func Extract[T any](ctx context.Context, client *Client, model string, prompt string) (T, error) {
+	return ExtractMessages[T](ctx, client, model, []api.Message{
+		{Role: "user", Content: prompt},
+	})
+}
+
+// ExtractMessages is like Extract with a full conversation, for instance a
+// system message carrying the data to extract from.
+func ExtractMessages[T any](ctx context.Context, client *Client, model string, messages []api.Message) (T, error) {
+	extraction, err := ExtractResult[T](ctx, client, model, messages)
+	if err != nil {
+		var data T
+		return data, err
+	}
+	return extraction.Data, nil
+}
+
+// Extraction is the decoded answer of ExtractResult, with the Result it
+// comes from (raw JSON, model, metrics...).
+type Extraction[T any] struct {
+	Data T
+	Result
+}
+
+// ExtractResult is like ExtractMessages, and also returns the Result of
+// the chat, e.g. to profile models with its Metrics:
+//
+//	extraction, err := structured.ExtractResult[Animal](ctx, client, "granite3-moe:1b", messages)
+//	fmt.Printf("%.1f tokens/s\n", extraction.Metrics.TokensPerSecond())
+func ExtractResult[T any](ctx context.Context, client *Client, model string, messages []api.Message) (*Extraction[T], error) {
+	schema, err := SchemaOf[T]()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := client.run(ctx, model, messages, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	extraction := &Extraction[T]{Result: *result}
+	if err := json.Unmarshal([]byte(result.Raw), &extraction.Data); err != nil {
+		return nil, fmt.Errorf("structured: cannot decode the model answer: %w", err)
+	}
+	return extraction, nil
+}
@@ -0,0 +1,201 @@
+package structured
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"01-json-output/internal/mockollama"
+
+	"github.com/ollama/ollama/api"
+)
+
+type testAnimal struct {
+	ScientificName string   `json:"scientific_name"`
+	AverageLength  float64  `json:"average_length" jsonschema:"minimum=0"`
+	Countries      []string `json:"countries"`
+}
+
+func userMessages(prompt string) []api.Message {
+	return []api.Message{{Role: "user", Content: prompt}}
+}
+
+func TestExtract(t *testing.T) {
+	client, server := newTestClient(t, mockollama.Response{
+		Content: `{"scientific_name":"Gallus gallus","average_length":1.5,"countries":["China","India"]}`,
+	})
+
+	animal, err := Extract[testAnimal](context.Background(), client, "granite3-moe:1b", "Tell me about chicken")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if animal.ScientificName != "Gallus gallus" || animal.AverageLength != 1.5 || len(animal.Countries) != 2 {
+		t.Errorf("animal = %+v", animal)
+	}
+	if !strings.Contains(string(server.Requests()[0].Format), `"scientific_name"`) {
+		t.Errorf("format = %s", server.Requests()[0].Format)
+	}
+}
+
+func TestExtractRetries(t *testing.T) {
+	client, server := newTestClient(t,
+		mockollama.Response{Content: `{"scientific_name":"Gallus gallus"}`},
+		mockollama.Response{Content: `{"scientific_name":"Gallus gallus","average_length":1.5,"countries":[]}`},
+	)
+	client.MaxRetries = 1
+
+	animal, err := Extract[testAnimal](context.Background(), client, "granite3-moe:1b", "chicken")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if animal.AverageLength != 1.5 {
+		t.Errorf("animal = %+v", animal)
+	}
+	requests := server.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("%d requests, want 2", len(requests))
+	}
+	// blind retry: the same question is asked again
+	if len(requests[1].Messages) != 1 {
+		t.Errorf("retry messages = %+v", requests[1].Messages)
+	}
+}
+
+func TestExtractValidationError(t *testing.T) {
+	raw := `{"scientific_name":"Gallus gallus","average_length":-1}`
+	client, server := newTestClient(t, mockollama.Response{Content: raw})
+	client.MaxRetries = 2
+
+	_, err := Extract[testAnimal](context.Background(), client, "granite3-moe:1b", "chicken")
+
+	var invalid *ValidationError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("err = %v, want a *ValidationError", err)
+	}
+	if invalid.Raw != raw || invalid.Attempts != 3 || len(server.Requests()) != 3 {
+		t.Errorf("raw = %s, attempts = %d, requests = %d", invalid.Raw, invalid.Attempts, len(server.Requests()))
+	}
+	want := []Violation{
+		{Path: "countries", Message: "missing required field"},
+		{Path: "average_length", Message: "-1 is lower than the minimum 0"},
+	}
+	if len(invalid.Violations) != len(want) {
+		t.Fatalf("violations = %v", invalid.Violations)
+	}
+	for i := range want {
+		if invalid.Violations[i] != want[i] {
+			t.Errorf("violation %d = %v, want %v", i, invalid.Violations[i], want[i])
+		}
+	}
+}
+
+func TestExtractRepair(t *testing.T) {
+	invalid := `{"scientific_name":"Gallus gallus","average_length":"1.5"}`
+	client, server := newTestClient(t,
+		mockollama.Response{Content: invalid},
+		mockollama.Response{Content: `{"scientific_name":"Gallus gallus","average_length":1.5,"countries":["China"]}`},
+	)
+	client.MaxRetries = 1
+	client.Repair = true
+
+	if _, err := Extract[testAnimal](context.Background(), client, "granite3-moe:1b", "chicken"); err != nil {
+		t.Fatal(err)
+	}
+
+	messages := server.Requests()[1].Messages
+	if len(messages) != 3 {
+		t.Fatalf("repair messages = %+v", messages)
+	}
+	if messages[1].Role != "assistant" || messages[1].Content != invalid {
+		t.Errorf("assistant message = %+v", messages[1])
+	}
+	for _, want := range []string{"countries: missing required field", "average_length: expected number, got string"} {
+		if !strings.Contains(messages[2].Content, want) {
+			t.Errorf("repair prompt %q does not mention %q", messages[2].Content, want)
+		}
+	}
+}
+
+func TestExtractInvalidJSON(t *testing.T) {
+	client, _ := newTestClient(t, mockollama.Response{Content: "I do not know this animal."})
+
+	_, err := Extract[testAnimal](context.Background(), client, "granite3-moe:1b", "chicken")
+	var invalid *ValidationError
+	if !errors.As(err, &invalid) || !strings.HasPrefix(invalid.Violations[0].Message, "invalid JSON") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestChatFallbacks(t *testing.T) {
+	client, server := newTestClient(t,
+		mockollama.Response{Content: `{}`},
+		mockollama.Response{Content: `{}`},
+		mockollama.Response{Content: `{"name":"chicken"}`},
+	)
+	client.MaxRetries = 1
+	client.Fallbacks = []string{"llama3.2:3b", "qwen2.5:7b"}
+
+	result, err := client.Chat(context.Background(), "granite3-moe:1b", userMessages("chicken"), nameSchema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Model != "llama3.2:3b" || result.Raw != `{"name":"chicken"}` {
+		t.Errorf("model = %s, raw = %s", result.Model, result.Raw)
+	}
+	var models []string
+	for _, req := range server.Requests() {
+		models = append(models, req.Model)
+	}
+	if strings.Join(models, " ") != "granite3-moe:1b granite3-moe:1b llama3.2:3b" {
+		t.Errorf("models = %v", models)
+	}
+}
+
+func TestChatFallbacksExhausted(t *testing.T) {
+	client, server := newTestClient(t, mockollama.Response{Content: `{}`})
+	client.Fallbacks = []string{"llama3.2:3b"}
+
+	_, err := client.Chat(context.Background(), "granite3-moe:1b", userMessages("chicken"), nameSchema())
+	var invalid *ValidationError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("err = %v, want a *ValidationError", err)
+	}
+	if len(server.Requests()) != 2 {
+		t.Errorf("%d requests, want 2", len(server.Requests()))
+	}
+}
+
+func TestExtractResultMetrics(t *testing.T) {
+	client, _ := newTestClient(t,
+		mockollama.Response{Content: `{}`, PromptEvalCount: 20, EvalCount: 3},
+		mockollama.Response{Content: `{"name":"chicken"}`, PromptEvalCount: 30, EvalCount: 6},
+	)
+	client.MaxRetries = 1
+
+	type named struct {
+		Name string `json:"name"`
+	}
+	extraction, err := ExtractResult[named](context.Background(), client, "granite3-moe:1b", userMessages("chicken"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if extraction.Data.Name != "chicken" || extraction.Raw != `{"name":"chicken"}` {
+		t.Errorf("extraction = %+v", extraction)
+	}
+	metrics := extraction.Metrics
+	if metrics.Requests != 2 || metrics.PromptTokens != 50 || metrics.OutputTokens != 9 || metrics.WallTime <= 0 || metrics.Cached {
+		t.Errorf("metrics = %+v", metrics)
+	}
+}
+
+func TestMetricsRates(t *testing.T) {
+	m := Metrics{PromptTokens: 100, OutputTokens: 50, PromptEvalDuration: time.Second / 2, EvalDuration: 2 * time.Second}
+	if m.TokensPerSecond() != 25 || m.PromptTokensPerSecond() != 200 {
+		t.Errorf("rates = %v, %v", m.TokensPerSecond(), m.PromptTokensPerSecond())
+	}
+	if (Metrics{}).TokensPerSecond() != 0 {
+		t.Error("rate without duration")
+	}
+}
@@ -0,0 +1,69 @@
+package structured
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimiter limits the chat requests sent with a context, see
+// WithRateLimiter. It is a token bucket: tokens are added at rate per
+// second, up to burst, and each request takes one.
+type RateLimiter struct {
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter returns a limiter of rate requests per second, burst of
+// them being sent at once (1 when not set).
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// Wait blocks until a token is available or ctx is done.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	// the token is taken now, the debt is paid by waiting
+	l.tokens--
+	wait := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type rateLimiterKey struct{}
+
+// WithRateLimiter returns a context in which every chat request waits for
+// limiter: the retries, repairs, voting samples, strategy passes,
+// fallbacks and explanations of a Chat are limited one by one.
+func WithRateLimiter(ctx context.Context, limiter *RateLimiter) context.Context {
+	return context.WithValue(ctx, rateLimiterKey{}, limiter)
+}
+
+// waitRateLimiter waits for the rate limiter of ctx, if any.
+func waitRateLimiter(ctx context.Context) error {
+	if l, ok := ctx.Value(rateLimiterKey{}).(*RateLimiter); ok && l != nil {
+		return l.Wait(ctx)
+	}
+	return nil
+}
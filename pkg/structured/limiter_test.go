@@ -0,0 +1,45 @@
+package structured
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"01-json-output/internal/mockollama"
+)
+
+func TestRateLimiterPerRequest(t *testing.T) {
+	client, server := newTestClient(t, mockollama.Response{Content: `{}`})
+	client.MaxRetries = 2
+	ctx := WithRateLimiter(context.Background(), NewRateLimiter(20, 1))
+
+	start := time.Now()
+	_, err := client.Chat(ctx, "granite3-moe:1b", userMessages("chicken"), nameSchema())
+	var invalid *ValidationError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("err = %v", err)
+	}
+	// the 2 retries wait for a token at 20 per second
+	if n := len(server.Requests()); n != 3 {
+		t.Errorf("%d requests, want 3", n)
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("elapsed = %s, the retries are not limited", elapsed)
+	}
+}
+
+func TestRateLimiterCanceled(t *testing.T) {
+	client, server := newTestClient(t, mockollama.Response{Content: `{"name":"chicken"}`})
+	limiter := NewRateLimiter(0.1, 1)
+	limiter.Wait(context.Background()) // the token of the next 10s
+
+	ctx, cancel := context.WithTimeout(WithRateLimiter(context.Background(), limiter), 20*time.Millisecond)
+	defer cancel()
+	if _, err := client.Chat(ctx, "granite3-moe:1b", userMessages("chicken"), nameSchema()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want the deadline", err)
+	}
+	if n := len(server.Requests()); n != 0 {
+		t.Errorf("%d requests sent", n)
+	}
+}
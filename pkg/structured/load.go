@@ -0,0 +1,171 @@
+package structured
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadSchema reads a JSON schema from a .json, .yaml or .yml file and
+// checks it with CheckSchema. The result can be used as the schema of
+// JSONChat, Chat or Validate.
+func LoadSchema(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var schema map[string]any
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		var document any
+		if err := yaml.Unmarshal(data, &document); err != nil {
+			return nil, fmt.Errorf("structured: %s: %w", path, err)
+		}
+		// go through JSON to get the same types as a JSON file
+		if data, err = json.Marshal(document); err != nil {
+			return nil, fmt.Errorf("structured: %s: %w", path, err)
+		}
+		fallthrough
+	case ".json":
+		if err := json.Unmarshal(data, &schema); err != nil {
+			return nil, fmt.Errorf("structured: %s: %w", path, err)
+		}
+	default:
+		return nil, fmt.Errorf("structured: %s: unknown schema file type, .json, .yaml or .yml expected", path)
+	}
+
+	if err := CheckSchema(schema); err != nil {
+		return nil, fmt.Errorf("%w (%s)", err, path)
+	}
+	return schema, nil
+}
+
+var schemaTypeNames = map[string]bool{
+	"string": true, "number": true, "integer": true, "boolean": true,
+	"array": true, "object": true, "null": true,
+}
+
+// CheckSchema verifies that schema is a legal JSON schema: known types,
+// objects where sub-schemas are expected, required fields that exist,
+// numeric bounds and lengths, enums and valid patterns.
+func CheckSchema(schema map[string]any) error {
+	if schema == nil {
+		return fmt.Errorf("structured: invalid schema: empty")
+	}
+	var problems []string
+	checkSchema(schema, "", &problems)
+	if len(problems) > 0 {
+		return fmt.Errorf("structured: invalid schema: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func checkSchema(schema map[string]any, path string, problems *[]string) {
+	report := func(format string, args ...any) {
+		where := path
+		if where == "" {
+			where = "(root)"
+		}
+		*problems = append(*problems, where+": "+fmt.Sprintf(format, args...))
+	}
+
+	if t, ok := schema["type"]; ok {
+		types := schemaTypes(t)
+		if len(types) == 0 {
+			report("type must be a string or an array of strings")
+		}
+		for _, name := range types {
+			if !schemaTypeNames[name] {
+				report("unknown type %q", name)
+			}
+		}
+	}
+
+	if p, ok := schema["properties"]; ok {
+		properties, isObject := p.(map[string]any)
+		if !isObject {
+			report("properties must be an object")
+		}
+		for name, property := range properties {
+			sub, isObject := property.(map[string]any)
+			if !isObject {
+				report("property %q must be a schema object", name)
+				continue
+			}
+			checkSchema(sub, joinPath(path, name), problems)
+		}
+		if r, ok := schema["required"]; ok {
+			required, isArray := r.([]any)
+			if !isArray {
+				if _, isStrings := r.([]string); !isStrings {
+					report("required must be an array of strings")
+				}
+			}
+			for _, name := range required {
+				if _, isString := name.(string); !isString {
+					report("required must be an array of strings")
+				}
+			}
+			for _, name := range toStrings(r) {
+				if _, ok := properties[name]; !ok {
+					report("required field %q is not a property", name)
+				}
+			}
+		}
+	}
+
+	if i, ok := schema["items"]; ok {
+		items, isObject := i.(map[string]any)
+		if !isObject {
+			report("items must be a schema object")
+		} else {
+			checkSchema(items, path+"[]", problems)
+		}
+	}
+
+	for _, keyword := range []string{"minimum", "maximum"} {
+		if v, ok := schema[keyword]; ok {
+			if _, isNumber := toFloat(v); !isNumber {
+				report("%s must be a number", keyword)
+			}
+		}
+	}
+
+	for _, keyword := range []string{"minLength", "maxLength"} {
+		if v, ok := schema[keyword]; ok {
+			if n, isNumber := toFloat(v); !isNumber || n < 0 || n != math.Trunc(n) {
+				report("%s must be a non-negative integer", keyword)
+			}
+		}
+	}
+
+	if e, ok := schema["enum"]; ok {
+		_, isArray := e.([]any)
+		_, isStrings := e.([]string)
+		if !isArray && !isStrings {
+			report("enum must be an array")
+		}
+	}
+
+	if f, ok := schema["format"]; ok {
+		if _, isString := f.(string); !isString {
+			report("format must be a string")
+		}
+	}
+
+	if p, ok := schema["pattern"]; ok {
+		pattern, isString := p.(string)
+		if !isString {
+			report("pattern must be a string")
+		} else if _, err := regexp.Compile(pattern); err != nil {
+			report("invalid pattern: %v", err)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package structured
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadSchema(t *testing.T) {
+	files := map[string]string{
+		"animal.json": `{"type": "object", "properties": {"legs": {"type": "integer", "minimum": 0}}, "required": ["legs"]}`,
+		"animal.yaml": "type: object\nproperties:\n  legs:\n    type: integer\n    minimum: 0\nrequired: [legs]\n",
+	}
+	for name, content := range files {
+		schema, err := LoadSchema(writeFile(t, name, content))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		violations, _ := ValidateJSON(schema, `{"legs": -2}`)
+		if len(violations) != 1 {
+			t.Errorf("%s: violations = %v", name, violations)
+		}
+	}
+}
+
+func TestLoadSchemaErrors(t *testing.T) {
+	tests := []struct {
+		name, content, want string
+	}{
+		{"schema.txt", `{}`, "unknown schema file type"},
+		{"bad.json", `{"type": `, "unexpected end of JSON input"},
+		{"type.yaml", "type: objet\n", `unknown type "objet"`},
+		{"required.json", `{"type": "object", "properties": {}, "required": ["name"]}`, `required field "name" is not a property`},
+		{"pattern.json", `{"type": "string", "pattern": "[a-"}`, "invalid pattern"},
+		{"length.json", `{"type": "string", "minLength": -1}`, "minLength must be a non-negative integer"},
+	}
+	for _, tt := range tests {
+		_, err := LoadSchema(writeFile(t, tt.name, tt.content))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package structured
+
+// Options are the model options sent with every chat request. Unset fields
+// (nil pointers, zero values) are left to the model defaults; pointers are
+// used where zero is a meaningful value, see Float and Int.
+type Options struct {
+	Temperature   *float64
+	TopK          int
+	TopP          *float64
+	MinP          *float64
+	Seed          *int
+	NumCtx        int
+	NumPredict    int
+	RepeatLastN   *int
+	RepeatPenalty *float64
+	Stop          []string
+
+	// Extra holds the options without a field, by their Ollama name.
+	// Fields take precedence over Extra.
+	Extra map[string]any
+}
+
+// DefaultOptions returns the options of NewClient: a temperature of 0 and
+// repeat_last_n of 2, for stable answers.
+func DefaultOptions() Options {
+	return Options{
+		Temperature: Float(0.0),
+		RepeatLastN: Int(2),
+	}
+}
+
+// Float returns a pointer to v, for the float options.
+func Float(v float64) *float64 {
+	return &v
+}
+
+// Int returns a pointer to v, for the integer options.
+func Int(v int) *int {
+	return &v
+}
+
+// ToMap converts the options to the map of the Ollama API, keyed by the
+// option names (temperature, repeat_last_n, ...).
+func (o Options) ToMap() map[string]any {
+	m := make(map[string]any, len(o.Extra)+10)
+	for name, value := range o.Extra {
+		m[name] = value
+	}
+	if o.Temperature != nil {
+		m["temperature"] = *o.Temperature
+	}
+	if o.TopK != 0 {
+		m["top_k"] = o.TopK
+	}
+	if o.TopP != nil {
+		m["top_p"] = *o.TopP
+	}
+	if o.MinP != nil {
+		m["min_p"] = *o.MinP
+	}
+	if o.Seed != nil {
+		m["seed"] = *o.Seed
+	}
+	if o.NumCtx != 0 {
+		m["num_ctx"] = o.NumCtx
+	}
+	if o.NumPredict != 0 {
+		m["num_predict"] = o.NumPredict
+	}
+	if o.RepeatLastN != nil {
+		m["repeat_last_n"] = *o.RepeatLastN
+	}
+	if o.RepeatPenalty != nil {
+		m["repeat_penalty"] = *o.RepeatPenalty
+	}
+	if len(o.Stop) > 0 {
+		m["stop"] = o.Stop
+	}
+	return m
+}
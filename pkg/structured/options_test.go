@@ -0,0 +1,34 @@
+package structured
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOptionsToMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		want    map[string]any
+	}{
+		{"empty", Options{}, map[string]any{}},
+		{"default", DefaultOptions(), map[string]any{"temperature": 0.0, "repeat_last_n": 2}},
+		{
+			"fields",
+			Options{TopP: Float(0.9), Seed: Int(0), NumCtx: 4096, NumPredict: -1, Stop: []string{"}"}},
+			map[string]any{"top_p": 0.9, "seed": 0, "num_ctx": 4096, "num_predict": -1, "stop": []string{"}"}},
+		},
+		{
+			"extra",
+			Options{Temperature: Float(0.5), Extra: map[string]any{"temperature": 1.0, "mirostat": 2}},
+			map[string]any{"temperature": 0.5, "mirostat": 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.options.ToMap(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ToMap() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package structured
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+// PerField asks the model one top-level field at a time, with the same
+// conversation, and assembles the object client-side. It is slower (one
+// request per field) but much more reliable with 1B models on wide schemas.
+type PerField struct {
+	// OnField, when set, is called after each field request with its cost.
+	OnField func(FieldCost)
+}
+
+// FieldCost is the cost of the request made for one field by PerField.
+type FieldCost struct {
+	Field        string
+	Duration     time.Duration
+	PromptTokens int
+	OutputTokens int
+}
+
+// PerFieldPrompt asks for a single field, it is formatted with the field
+// name and its description (possibly empty).
+var PerFieldPrompt = "Give only the value of the %q JSON field. %s"
+
+func (PerField) Name() string { return "per-field" }
+
+func (s PerField) Generate(ctx context.Context, c *Client, model string, messages []api.Message, schema any) (string, error) {
+	object, err := schemaMap(schema)
+	if err != nil {
+		return "", err
+	}
+	properties, _ := object["properties"].(map[string]any)
+	if len(properties) == 0 {
+		return "", fmt.Errorf("structured: per-field strategy needs an object schema with properties")
+	}
+
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := map[string]json.RawMessage{}
+	for _, name := range names {
+		property, _ := properties[name].(map[string]any)
+		description, _ := property["description"].(string)
+
+		fieldSchema := map[string]any{
+			"type":       "object",
+			"properties": map[string]any{name: property},
+			"required":   []string{name},
+		}
+		format, err := json.Marshal(fieldSchema)
+		if err != nil {
+			return "", err
+		}
+
+		prompt := append([]api.Message{}, messages...)
+		prompt = append(prompt, api.Message{Role: "user", Content: fmt.Sprintf(PerFieldPrompt, name, description)})
+
+		start := time.Now()
+		resp, err := c.chat(ctx, &api.ChatRequest{Model: model, Messages: prompt, Format: format})
+		if err != nil {
+			return "", fmt.Errorf("structured: field %s: %w", name, err)
+		}
+		if s.OnField != nil {
+			s.OnField(FieldCost{
+				Field:        name,
+				Duration:     time.Since(start),
+				PromptTokens: resp.PromptEvalCount,
+				OutputTokens: resp.EvalCount,
+			})
+		}
+
+		var answer map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(resp.Message.Content), &answer); err != nil {
+			return "", fmt.Errorf("structured: field %s: invalid answer: %w", name, err)
+		}
+		if value, ok := answer[name]; ok {
+			result[name] = value
+		}
+	}
+
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return "", err
+	}
+	return string(raw), nil
+}
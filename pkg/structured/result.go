@@ -0,0 +1,73 @@
+package structured
+
+import (
+	"context"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+// ExplainPrompt is the follow-up message used to get the model rationale
+// when Client.Explain is set.
+var ExplainPrompt = "Explain briefly, in plain text, how you got the values of your previous answer."
+
+// Result is the answer of a structured chat.
+type Result struct {
+	// Raw is the JSON answer of the model.
+	Raw string
+
+	// Model is the model that produced Raw: the requested one, or one of
+	// Client.Fallbacks.
+	Model string
+
+	// Strategy is the name of the strategy that produced Raw, and
+	// Enforcement how strictly the server enforced the format.
+	Strategy    string
+	Enforcement Enforcement
+
+	// Confidence is the share of the samples agreeing with the value of
+	// each top-level field, only set when Client.Voting is.
+	Confidence map[string]float64
+
+	// Explanation is the free-text rationale of the model, only set when
+	// Client.Explain is. It is asked for in a second message, so it is
+	// never part of Raw.
+	Explanation string
+
+	// Metrics are the tokens and durations of the requests made for the
+	// result, including the explanation.
+	Metrics Metrics
+}
+
+// Chat gets the JSON answer with the strategy of the client, validates it
+// against schema (see Client.MaxRetries) and returns a Result, including
+// the model explanation when c.Explain is set.
+func (c *Client) Chat(ctx context.Context, model string, messages []api.Message, schema any) (*Result, error) {
+	result, err := c.run(ctx, model, messages, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Explain {
+		start := time.Now()
+		counted, u := withUsage(ctx)
+		result.Explanation, err = c.explain(counted, result.Model, messages, result.Raw)
+		if err != nil {
+			return nil, err
+		}
+		result.Metrics.add(u.Metrics())
+		result.Metrics.WallTime += time.Since(start)
+	}
+	return result, nil
+}
+
+// explain continues the conversation with the JSON answer and asks the
+// model, without any format constraint, why it answered that.
+func (c *Client) explain(ctx context.Context, model string, messages []api.Message, answer string) (string, error) {
+	followUp := append([]api.Message{}, messages...)
+	followUp = append(followUp,
+		api.Message{Role: "assistant", Content: answer},
+		api.Message{Role: "user", Content: ExplainPrompt},
+	)
+	return c.TextChat(ctx, model, followUp)
+}
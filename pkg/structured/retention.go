@@ -0,0 +1,118 @@
+package structured
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Retention limits the size of a FileCache. Zero values don't limit.
+type Retention struct {
+	// MaxAge removes the results older than it.
+	MaxAge time.Duration
+	// MaxEntries and MaxBytes remove the oldest results until the cache
+	// holds at most that many results, or bytes.
+	MaxEntries int
+	MaxBytes   int64
+}
+
+// PruneStats tells what Prune removed.
+type PruneStats struct {
+	Removed int
+	Bytes   int64
+	// Kept and KeptBytes describe the cache after pruning.
+	Kept      int
+	KeptBytes int64
+}
+
+// Prune removes the results of the cache exceeding the retention r (and
+// the leftovers of interrupted writes). The age of a result is the age of
+// its file.
+func (f *FileCache) Prune(r Retention) (PruneStats, error) {
+	var stats PruneStats
+	dirEntries, err := os.ReadDir(f.Dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return stats, nil
+	}
+	if err != nil {
+		return stats, err
+	}
+
+	type file struct {
+		path     string
+		size     int64
+		modified time.Time
+	}
+	var files []file
+	var errs []error
+	remove := func(path string, size int64) {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+			return
+		}
+		stats.Removed++
+		stats.Bytes += size
+	}
+
+	maxAge := r.MaxAge
+	if maxAge == 0 {
+		maxAge = f.TTL
+	}
+	for _, entry := range dirEntries {
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		path := filepath.Join(f.Dir, entry.Name())
+		switch {
+		case strings.HasSuffix(entry.Name(), ".tmp"):
+			// an interrupted write, unless it is in progress
+			if time.Since(info.ModTime()) > time.Minute {
+				remove(path, info.Size())
+			}
+		case !strings.HasSuffix(entry.Name(), ".json"):
+		case expired(info.ModTime(), maxAge):
+			remove(path, info.Size())
+		default:
+			files = append(files, file{path, info.Size(), info.ModTime()})
+		}
+	}
+
+	// newest first: the oldest ones are removed past the limits
+	sort.Slice(files, func(i, j int) bool { return files[i].modified.After(files[j].modified) })
+	full := false
+	for i, file := range files {
+		full = full || (r.MaxEntries > 0 && i >= r.MaxEntries) || (r.MaxBytes > 0 && stats.KeptBytes+file.size > r.MaxBytes)
+		if full {
+			remove(file.path, file.size)
+			continue
+		}
+		stats.Kept++
+		stats.KeptBytes += file.size
+	}
+	return stats, errors.Join(errs...)
+}
+
+// Janitor prunes the cache every interval until ctx is done, reporting
+// the errors to onError if not nil. It is meant to run in a goroutine of
+// long-running programs:
+//
+//	go cache.Janitor(ctx, time.Hour, structured.Retention{MaxAge: 7 * 24 * time.Hour}, nil)
+func (f *FileCache) Janitor(ctx context.Context, interval time.Duration, r Retention, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := f.Prune(r); err != nil && onError != nil {
+			onError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
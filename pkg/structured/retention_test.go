@@ -0,0 +1,73 @@
+package structured
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCacheTTL(t *testing.T) {
+	caches := map[string]Cache{
+		"memory": &MemoryCache{TTL: time.Millisecond, entries: map[string]memoryEntry{}},
+		"file":   &FileCache{Dir: t.TempDir(), TTL: time.Millisecond},
+	}
+	for name, cache := range caches {
+		cache.Put("key", &Result{Raw: `{}`})
+		time.Sleep(5 * time.Millisecond)
+		if _, ok := cache.Get("key"); ok {
+			t.Errorf("%s: expired result returned", name)
+		}
+	}
+}
+
+func TestFilePrune(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name      string
+		retention Retention
+		kept      []string
+	}{
+		{"no limit", Retention{}, []string{"a", "b", "c", "d"}},
+		{"max age", Retention{MaxAge: 90 * time.Minute}, []string{"a", "b"}},
+		{"max entries", Retention{MaxEntries: 3}, []string{"a", "b", "c"}},
+		{"max bytes", Retention{MaxBytes: 25}, []string{"a", "b"}},
+		{"all", Retention{MaxAge: 3 * time.Hour, MaxEntries: 1}, []string{"a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := &FileCache{Dir: t.TempDir()}
+			// a is the newest result, d the oldest
+			for i, key := range []string{"a", "b", "c", "d"} {
+				path := cache.path(key)
+				if err := os.WriteFile(path, []byte(`{"raw":"{}"}`), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				modified := now.Add(-time.Duration(i) * time.Hour)
+				if err := os.Chtimes(path, modified, modified); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			stats, err := cache.Prune(tt.retention)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stats.Kept != len(tt.kept) || stats.Removed != 4-len(tt.kept) {
+				t.Errorf("stats = %+v", stats)
+			}
+			for _, key := range tt.kept {
+				if _, err := os.Stat(cache.path(key)); err != nil {
+					t.Errorf("%s removed", key)
+				}
+			}
+		})
+	}
+}
+
+func TestFilePruneMissingDir(t *testing.T) {
+	cache := &FileCache{Dir: filepath.Join(t.TempDir(), "cache")}
+	if _, err := cache.Prune(Retention{MaxEntries: 1}); err != nil {
+		t.Error(err)
+	}
+}
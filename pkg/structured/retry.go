@@ -0,0 +1,77 @@
+package structured
+
+import (
+	"context"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+)
+
+// RepairPrompt introduces the list of violations sent back to the model
+// when Client.Repair is set.
+var RepairPrompt = "Your answer does not match the JSON schema. Fix these errors and answer again with the complete JSON:"
+
+// truncatedViolation is reported for the answers cut by the output token
+// limit (done_reason "length").
+var truncatedViolation = Violation{Message: "answer truncated at the output token limit (num_predict or num_ctx)"}
+
+// generateValid runs the strategy for schema and validates its answer,
+// trying again up to c.MaxRetries times when it doesn't match the schema or
+// was truncated.
+func (c *Client) generateValid(ctx context.Context, model string, messages []api.Message, schema any) (*Result, error) {
+	var (
+		raw        string
+		strategy   Strategy
+		violations []Violation
+		err        error
+	)
+	prompt := messages
+	for attempt := 1; attempt <= c.MaxRetries+1; attempt++ {
+		attemptCtx, u := withUsage(ctx)
+		raw, strategy, err = c.generate(attemptCtx, model, prompt, schema)
+		if err != nil {
+			return nil, err
+		}
+		result := &Result{Raw: c.clean(raw), Model: model, Strategy: strategy.Name(), Enforcement: enforcementOf(strategy)}
+		switch {
+		case u.Metrics().Truncated > 0:
+			// a cut answer may still parse once cleaned, with values cut
+			// short: it is never accepted
+			violations = []Violation{truncatedViolation}
+		case schema == nil:
+			return result, nil
+		default:
+			violations, err = ValidateJSON(schema, result.Raw)
+			if err != nil {
+				return nil, err
+			}
+			if len(violations) == 0 {
+				return result, nil
+			}
+		}
+
+		c.log().InfoContext(ctx, "invalid answer",
+			"model", model, "attempt", attempt, "attempts", c.MaxRetries+1, "violations", len(violations))
+		if c.Repair {
+			prompt = repairMessages(messages, raw, violations)
+		}
+	}
+	return nil, &ValidationError{Raw: raw, Attempts: c.MaxRetries + 1, Violations: violations}
+}
+
+// repairMessages continues the conversation with the invalid answer and
+// the violations, so the model can correct its own output.
+func repairMessages(messages []api.Message, raw string, violations []Violation) []api.Message {
+	var errors strings.Builder
+	errors.WriteString(RepairPrompt)
+	for _, v := range violations {
+		errors.WriteString("\n- ")
+		errors.WriteString(v.String())
+	}
+
+	repair := append([]api.Message{}, messages...)
+	return append(repair,
+		api.Message{Role: "assistant", Content: raw},
+		api.Message{Role: "user", Content: errors.String()},
+	)
+}
@@ -0,0 +1,103 @@
+package structured
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+// run is the common path of Chat and Extract: it gets a valid answer (from
+// the cache if any), records it for drift monitoring and mirrors the
+// request to the shadow model if any. The metrics of the result count the
+// requests made.
+func (c *Client) run(ctx context.Context, model string, messages []api.Message, schema any) (*Result, error) {
+	start := time.Now()
+
+	var key string
+	if c.Cache != nil {
+		var err error
+		if key, err = c.cacheKey(model, messages, schema); err != nil {
+			return nil, err
+		}
+		if result, ok := c.Cache.Get(key); ok {
+			c.log().DebugContext(ctx, "cached result", "model", model, "key", key)
+			result.Metrics = Metrics{WallTime: time.Since(start), Cached: true}
+			return result, nil
+		}
+	}
+
+	counted, u := withUsage(ctx)
+	var result *Result
+	var err error
+	if c.Voting != nil && c.Voting.Samples > 1 {
+		result, err = c.vote(counted, model, messages, schema)
+	} else {
+		result, err = c.generateFallback(counted, model, messages, schema)
+	}
+	if err != nil {
+		return nil, err
+	}
+	result.Metrics = u.Metrics()
+	result.Metrics.WallTime = time.Since(start)
+	if c.Cache != nil {
+		c.Cache.Put(key, result)
+	}
+	if c.Drift != nil && schema != nil {
+		c.Drift.Observe(schemaTitle(schema), result.Raw)
+	}
+	if c.Shadow != nil {
+		c.Shadow.mirror(ctx, c, model, messages, schema, result)
+	}
+	return result, nil
+}
+
+// generateFallback gets a valid answer from model, or else from the first
+// of the fallback models that gives one. The *ValidationError of the last
+// model is returned when none does.
+func (c *Client) generateFallback(ctx context.Context, model string, messages []api.Message, schema any) (*Result, error) {
+	result, err := c.generateValid(ctx, model, messages, schema)
+	for _, fallback := range c.Fallbacks {
+		var invalid *ValidationError
+		if !errors.As(err, &invalid) {
+			break
+		}
+		c.log().InfoContext(ctx, "falling back", "model", model, "fallback", fallback)
+		model = fallback
+		result, err = c.generateValid(ctx, fallback, messages, schema)
+	}
+	return result, err
+}
+
+// clone returns a client sharing the connection and the settings of c.
+func (c *Client) clone() *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return &Client{
+		api:           c.api,
+		Options:       c.Options,
+		Explain:       c.Explain,
+		Strategy:      c.Strategy,
+		Strategies:    c.Strategies,
+		MaxRetries:    c.MaxRetries,
+		Fallbacks:     c.Fallbacks,
+		Voting:        c.Voting,
+		Logger:        c.Logger,
+		Repair:        c.Repair,
+		Strict:        c.Strict,
+		Capabilities:  c.Capabilities,
+		Shadow:        c.Shadow,
+		Drift:         c.Drift,
+		Cache:         c.Cache,
+		schemaSupport: c.schemaSupport,
+	}
+}
+
+// clean applies CleanJSON to raw unless the client is strict.
+func (c *Client) clean(raw string) string {
+	if c.Strict {
+		return raw
+	}
+	return CleanJSON(raw)
+}
@@ -0,0 +1,237 @@
+package structured
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SchemaOf returns the JSON schema of T. See SchemaFor.
+func SchemaOf[T any]() (map[string]any, error) {
+	var v T
+	return SchemaFor(v)
+}
+
+// SchemaFor builds a JSON schema (as used by structured outputs) from the
+// struct type of v.
+//
+// Nested structs and slices of structs become nested objects and arrays of
+// objects, time.Time a date-time string. Recursive types are not supported.
+//
+// Property names come from the json tags, fields without omitempty are
+// required, and the jsonschema tag adds keywords to the property
+// (description, title, minimum, maximum, minLength, maxLength, pattern,
+// format and enum):
+//
+//	ScientificName string  `json:"scientific_name" jsonschema:"description=scientific name of the animal,pattern=^[A-Z][a-z]+ [a-z]+$"`
+//	Diet           string  `json:"diet" jsonschema:"enum=herbivore|carnivore|omnivore"`
+//	AverageLength  float64 `json:"average_length" jsonschema:"minimum=0"`
+//
+// Values of the jsonschema tag are separated by commas, so they cannot
+// contain one.
+func SchemaFor(v any) (map[string]any, error) {
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return nil, fmt.Errorf("structured: cannot build a schema from nil")
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("structured: cannot build a schema from %s, a struct is expected", t)
+	}
+	schema, err := objectSchema(t, map[reflect.Type]bool{})
+	if err != nil {
+		return nil, err
+	}
+	// the title identifies the schema, e.g. to select a strategy
+	if t.Name() != "" {
+		schema["title"] = t.Name()
+	}
+	return schema, nil
+}
+
+// objectSchema builds the schema of a struct type. visiting holds the
+// struct types being built, to reject recursive types.
+func objectSchema(t reflect.Type, visiting map[reflect.Type]bool) (map[string]any, error) {
+	if visiting[t] {
+		return nil, fmt.Errorf("recursive type %s", t)
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	properties := map[string]any{}
+	required := []string{}
+
+	if err := addFields(t, properties, &required, visiting); err != nil {
+		return nil, err
+	}
+
+	return map[string]any{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}, nil
+}
+
+func addFields(t reflect.Type, properties map[string]any, required *[]string, visiting map[reflect.Type]bool) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		name, omitempty, skip := jsonName(field)
+		if skip {
+			continue
+		}
+
+		// fields of embedded structs are promoted, as with encoding/json
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if visiting[ft] {
+					return fmt.Errorf("structured: field %s: recursive type %s", field.Name, ft)
+				}
+				visiting[ft] = true
+				err := addFields(ft, properties, required, visiting)
+				delete(visiting, ft)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property, err := typeSchema(field.Type, visiting)
+		if err != nil {
+			return fmt.Errorf("structured: field %s: %w", field.Name, err)
+		}
+		if err := applyTags(property, field.Tag.Get("jsonschema")); err != nil {
+			return fmt.Errorf("structured: field %s: %w", field.Name, err)
+		}
+
+		properties[name] = property
+		if !omitempty {
+			*required = append(*required, name)
+		}
+	}
+	return nil
+}
+
+// jsonName reads the json tag of a field like encoding/json does.
+func jsonName(field reflect.StructField) (name string, omitempty bool, skip bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, true
+	}
+	parts := strings.Split(tag, ",")
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitempty = true
+		}
+	}
+	return parts[0], omitempty, false
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func typeSchema(t reflect.Type, visiting map[reflect.Type]bool) (map[string]any, error) {
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return typeSchema(t.Elem(), visiting)
+	case reflect.String:
+		return map[string]any{"type": "string"}, nil
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}, nil
+	case reflect.Slice, reflect.Array:
+		items, err := typeSchema(t.Elem(), visiting)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "array", "items": items}, nil
+	case reflect.Struct:
+		return objectSchema(t, visiting)
+	default:
+		return nil, fmt.Errorf("unsupported type %s", t)
+	}
+}
+
+// applyTags adds the keywords of a jsonschema struct tag to property.
+// The values of enum are separated by "|" and converted to the type of
+// the property.
+func applyTags(property map[string]any, tag string) error {
+	if tag == "" {
+		return nil
+	}
+	for _, item := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(item, "=")
+		key = strings.TrimSpace(key)
+
+		switch key {
+		case "description", "title", "pattern", "format":
+			property[key] = value
+		case "minimum", "maximum":
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("invalid %s %q", key, value)
+			}
+			property[key] = n
+		case "minLength", "maxLength":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return fmt.Errorf("invalid %s %q", key, value)
+			}
+			property[key] = n
+		case "enum":
+			enum, err := enumValues(property["type"], strings.Split(value, "|"))
+			if err != nil {
+				return err
+			}
+			property[key] = enum
+		default:
+			return fmt.Errorf("unknown jsonschema keyword %q", key)
+		}
+	}
+	return nil
+}
+
+func enumValues(t any, values []string) ([]any, error) {
+	enum := make([]any, len(values))
+	for i, v := range values {
+		switch t {
+		case "integer", "number":
+			n, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid enum value %q for a %s", v, t)
+			}
+			enum[i] = n
+		case "boolean":
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid enum value %q for a boolean", v)
+			}
+			enum[i] = b
+		default:
+			enum[i] = v
+		}
+	}
+	return enum, nil
+}
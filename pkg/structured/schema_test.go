@@ -0,0 +1,76 @@
+package structured
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSchemaOf(t *testing.T) {
+	type Country struct {
+		Name string `json:"name"`
+		Code string `json:"code,omitempty" jsonschema:"pattern=^[A-Z]{2}$"`
+	}
+	type Animal struct {
+		ScientificName string    `json:"scientific_name" jsonschema:"description=scientific name"`
+		Diet           string    `json:"diet" jsonschema:"enum=herbivore|carnivore"`
+		Legs           int       `json:"legs" jsonschema:"minimum=0,maximum=8"`
+		Countries      []Country `json:"countries"`
+		Ignored        string    `json:"-"`
+		internal       string
+	}
+
+	schema, err := SchemaOf[Animal]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"type":  "object",
+		"title": "Animal",
+		"properties": map[string]any{
+			"scientific_name": map[string]any{"type": "string", "description": "scientific name"},
+			"diet":            map[string]any{"type": "string", "enum": []any{"herbivore", "carnivore"}},
+			"legs":            map[string]any{"type": "integer", "minimum": 0.0, "maximum": 8.0},
+			"countries": map[string]any{"type": "array", "items": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"name": map[string]any{"type": "string"},
+					"code": map[string]any{"type": "string", "pattern": "^[A-Z]{2}$"},
+				},
+				"required": []string{"name"},
+			}},
+		},
+		"required": []string{"scientific_name", "diet", "legs", "countries"},
+	}
+	if !reflect.DeepEqual(schema, want) {
+		t.Errorf("schema = %v\nwant %v", schema, want)
+	}
+}
+
+func TestSchemaForErrors(t *testing.T) {
+	type Node struct {
+		Children []Node `json:"children"`
+	}
+	type Embedded struct {
+		*Embedded
+		X int `json:"x"`
+	}
+	type BadTag struct {
+		Name string `json:"name" jsonschema:"colour=red"`
+	}
+	type BadType struct {
+		Callback func() `json:"callback"`
+	}
+
+	for name, v := range map[string]any{
+		"nil":          nil,
+		"not a struct": 42,
+		"recursive":    Node{},
+		"embedded":     Embedded{},
+		"bad tag":      BadTag{},
+		"bad type":     BadType{},
+	} {
+		if _, err := SchemaFor(v); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package structured
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand/v2"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+// Shadow mirrors a share of the Chat and Extract requests to a candidate
+// model, strategy or prompt, and reports how its answers compare with the
+// primary ones. The candidate runs in the background: returned results and
+// latency are not affected.
+type Shadow struct {
+	// Model is the candidate model, the primary model when empty.
+	Model string
+	// Strategy is the candidate strategy, the one of the client when nil.
+	Strategy Strategy
+	// Messages, when set, rewrites the prompt sent to the candidate (for
+	// instance to try new instructions).
+	Messages func([]api.Message) []api.Message
+	// Rate is the share of requests mirrored, between 0 and 1.
+	Rate float64
+	// Report receives the comparison of each mirrored request.
+	Report func(ShadowReport)
+
+	// MaxConcurrent is the number of mirrored requests in progress at
+	// most, DefaultShadowConcurrency when 0. Requests sampled beyond are
+	// not mirrored.
+	MaxConcurrent int
+	// Timeout limits each mirrored request, DefaultShadowTimeout when 0.
+	Timeout time.Duration
+
+	wg      sync.WaitGroup
+	once    sync.Once
+	running chan struct{}
+}
+
+// DefaultShadowConcurrency and DefaultShadowTimeout bound the mirrored
+// requests, so a slow candidate can't pile them up.
+const (
+	DefaultShadowConcurrency = 4
+	DefaultShadowTimeout     = 2 * time.Minute
+)
+
+// ShadowReport compares the answer of the candidate with the primary one.
+type ShadowReport struct {
+	Schema         string
+	PrimaryModel   string
+	CandidateModel string
+
+	// Err is set when the candidate request failed; a *ValidationError
+	// means it answered but the answer doesn't match the schema.
+	Err error
+	// Agreement is the share of top-level fields with the same value in
+	// both answers, and Mismatches lists the other ones.
+	Agreement  float64
+	Mismatches []string
+}
+
+// Wait waits for the mirrored requests in progress.
+func (s *Shadow) Wait() {
+	s.wg.Wait()
+}
+
+func (s *Shadow) mirror(ctx context.Context, c *Client, model string, messages []api.Message, schema any, primary *Result) {
+	if s.Rate <= 0 || rand.Float64() >= s.Rate {
+		return
+	}
+	s.once.Do(func() {
+		s.running = make(chan struct{}, defaultInt(s.MaxConcurrent, DefaultShadowConcurrency))
+	})
+	select {
+	case s.running <- struct{}{}:
+	default:
+		c.log().DebugContext(ctx, "shadow busy, request not mirrored", "model", model)
+		return
+	}
+
+	candidate := c.clone()
+	candidate.Shadow = nil
+	candidate.Explain = false
+	candidate.Cache = nil
+	if s.Strategy != nil {
+		candidate.Strategy = s.Strategy
+		candidate.Strategies = nil
+	}
+	candidateModel := model
+	if s.Model != "" {
+		candidateModel = s.Model
+	}
+	if s.Messages != nil {
+		messages = s.Messages(messages)
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer func() { <-s.running }()
+
+		timeout := s.Timeout
+		if timeout == 0 {
+			timeout = DefaultShadowTimeout
+		}
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+		defer cancel()
+
+		report := ShadowReport{
+			Schema:         schemaTitle(schema),
+			PrimaryModel:   model,
+			CandidateModel: candidateModel,
+		}
+		result, err := candidate.generateValid(ctx, candidateModel, messages, schema)
+		if err != nil {
+			report.Err = err
+		} else {
+			report.Agreement, report.Mismatches = compareFields(primary.Raw, result.Raw)
+		}
+		if s.Report != nil {
+			s.Report(report)
+		}
+	}()
+}
+
+// compareFields compares the top-level fields of two JSON objects.
+func compareFields(a, b string) (float64, []string) {
+	// both answers are valid JSON, they passed the validation
+	var objectA, objectB map[string]any
+	json.Unmarshal([]byte(a), &objectA)
+	json.Unmarshal([]byte(b), &objectB)
+
+	names := map[string]bool{}
+	for name := range objectA {
+		names[name] = true
+	}
+	for name := range objectB {
+		names[name] = true
+	}
+	if len(names) == 0 {
+		return 1, nil
+	}
+
+	var mismatches []string
+	for name := range names {
+		if !reflect.DeepEqual(objectA[name], objectB[name]) {
+			mismatches = append(mismatches, name)
+		}
+	}
+	sort.Strings(mismatches)
+	return float64(len(names)-len(mismatches)) / float64(len(names)), mismatches
+}
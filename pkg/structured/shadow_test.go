@@ -0,0 +1,79 @@
+package structured
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"01-json-output/internal/mockollama"
+
+	"github.com/ollama/ollama/api"
+)
+
+func TestShadow(t *testing.T) {
+	client, server := newTestClient(t, mockollama.Response{Content: `{"name":"chicken","legs":2}`})
+	server.PushModel("candidate", mockollama.Response{Content: `{"name":"chicken","legs":4}`})
+	var mu sync.Mutex
+	var reports []ShadowReport
+	client.Shadow = &Shadow{
+		Model: "candidate",
+		Rate:  1,
+		Messages: func(messages []api.Message) []api.Message {
+			return append([]api.Message{{Role: "system", Content: "Be precise."}}, messages...)
+		},
+		Report: func(r ShadowReport) {
+			mu.Lock()
+			defer mu.Unlock()
+			reports = append(reports, r)
+		},
+	}
+
+	result, err := client.Chat(context.Background(), "primary", userMessages("chicken"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Shadow.Wait()
+	if result.Raw != `{"name":"chicken","legs":2}` {
+		t.Errorf("primary answer = %s", result.Raw)
+	}
+	if len(reports) != 1 {
+		t.Fatalf("reports = %+v", reports)
+	}
+	r := reports[0]
+	if r.Err != nil || r.PrimaryModel != "primary" || r.CandidateModel != "candidate" || r.Agreement != 0.5 || strings.Join(r.Mismatches, ",") != "legs" {
+		t.Errorf("report = %+v", r)
+	}
+	requests := server.Requests()
+	if len(requests) != 2 || requests[1].Model != "candidate" || requests[1].Messages[0].Content != "Be precise." {
+		t.Errorf("requests = %+v", requests)
+	}
+}
+
+func TestShadowRate(t *testing.T) {
+	client, server := newTestClient(t, mockollama.Response{Content: `{"name":"chicken"}`})
+	client.Shadow = &Shadow{Model: "candidate", Rate: 0, Report: func(ShadowReport) { t.Error("request mirrored at rate 0") }}
+	if _, err := client.Chat(context.Background(), "primary", userMessages("chicken"), nil); err != nil {
+		t.Fatal(err)
+	}
+	client.Shadow.Wait()
+	if n := len(server.Requests()); n != 1 {
+		t.Errorf("%d requests, want 1", n)
+	}
+}
+
+func TestShadowMaxConcurrent(t *testing.T) {
+	client, server := newTestClient(t, mockollama.Response{Content: `{"name":"chicken"}`})
+	client.Shadow = &Shadow{Model: "candidate", Rate: 1, MaxConcurrent: 1}
+	// a mirrored request still in progress
+	client.Shadow.once.Do(func() { client.Shadow.running = make(chan struct{}, 1) })
+	client.Shadow.running <- struct{}{}
+
+	if _, err := client.Chat(context.Background(), "primary", userMessages("chicken"), nil); err != nil {
+		t.Fatal(err)
+	}
+	client.Shadow.Wait()
+	if n := len(server.Requests()); n != 1 {
+		t.Errorf("%d requests, want the candidate skipped", n)
+	}
+}
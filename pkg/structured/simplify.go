@@ -0,0 +1,200 @@
+package structured
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+)
+
+// Simplified wraps a strategy for low-capability models: nested objects are
+// flattened ("address.city"), string enums become described strings, and
+// schemas wider than MaxProperties are split into sequential sub-requests.
+// The answers are reassembled into the shape of the original schema.
+type Simplified struct {
+	Inner Strategy
+	// MaxProperties is the maximum number of properties per request, 0 for
+	// no split.
+	MaxProperties int
+}
+
+func (s Simplified) Name() string { return "simplified(" + s.Inner.Name() + ")" }
+
+func (s Simplified) Enforcement() Enforcement { return enforcementOf(s.Inner) }
+
+func (s Simplified) Generate(ctx context.Context, c *Client, model string, messages []api.Message, schema any) (string, error) {
+	original, err := schemaMap(schema)
+	if err != nil {
+		return "", err
+	}
+	flat := SimplifySchema(original)
+
+	result := map[string]any{}
+	for _, part := range splitSchema(flat.Schema, s.MaxProperties) {
+		raw, err := s.Inner.Generate(ctx, c, model, messages, part)
+		if err != nil {
+			return "", err
+		}
+		var answer map[string]any
+		if err := json.Unmarshal([]byte(raw), &answer); err != nil {
+			return "", fmt.Errorf("structured: simplified answer: %w", err)
+		}
+		for k, v := range answer {
+			result[k] = v
+		}
+	}
+
+	restored, err := json.Marshal(flat.Restore(result))
+	if err != nil {
+		return "", err
+	}
+	return string(restored), nil
+}
+
+// FlatSchema is a simplified schema, see SimplifySchema.
+type FlatSchema struct {
+	Schema map[string]any
+	// paths of the flattened properties in the original object
+	paths map[string][]string
+}
+
+// SimplifySchema flattens the nested objects of an object schema and turns
+// string enums into strings described with their allowed values.
+func SimplifySchema(schema map[string]any) FlatSchema {
+	flat := FlatSchema{
+		Schema: map[string]any{"type": "object"},
+		paths:  map[string][]string{},
+	}
+	properties := map[string]any{}
+	required := []string{}
+	flatten(schema, nil, true, properties, &required, flat.paths)
+
+	flat.Schema["properties"] = properties
+	flat.Schema["required"] = required
+	if title, ok := schema["title"]; ok {
+		flat.Schema["title"] = title
+	}
+	return flat
+}
+
+func flatten(schema map[string]any, path []string, required bool, properties map[string]any, flatRequired *[]string, paths map[string][]string) {
+	props, _ := schema["properties"].(map[string]any)
+	requiredNames := map[string]bool{}
+	for _, name := range toStrings(schema["required"]) {
+		requiredNames[name] = true
+	}
+
+	for name, p := range props {
+		property, ok := p.(map[string]any)
+		if !ok {
+			continue
+		}
+		propertyPath := append(append([]string{}, path...), name)
+		isRequired := required && requiredNames[name]
+
+		if t, _ := property["type"].(string); t == "object" && property["properties"] != nil {
+			flatten(property, propertyPath, isRequired, properties, flatRequired, paths)
+			continue
+		}
+
+		key := strings.Join(propertyPath, ".")
+		properties[key] = simplifyProperty(property)
+		paths[key] = propertyPath
+		if isRequired {
+			*flatRequired = append(*flatRequired, key)
+		}
+	}
+	sort.Strings(*flatRequired)
+}
+
+// simplifyProperty turns an enum of strings into a string with a
+// description of the allowed values. Other enums are kept: the answer would
+// be a string where the schema expects a number or a boolean.
+func simplifyProperty(property map[string]any) map[string]any {
+	values, ok := property["enum"].([]string)
+	if !ok {
+		enum, _ := property["enum"].([]any)
+		for _, v := range enum {
+			s, isString := v.(string)
+			if !isString {
+				return property
+			}
+			values = append(values, s)
+		}
+	}
+	if len(values) == 0 {
+		return property
+	}
+
+	description, _ := property["description"].(string)
+	if description != "" {
+		description += " "
+	}
+	return map[string]any{
+		"type":        "string",
+		"description": description + "One of: " + strings.Join(values, ", ") + ".",
+	}
+}
+
+// Restore rebuilds the nested object from an answer to the flat schema.
+func (f FlatSchema) Restore(answer map[string]any) map[string]any {
+	restored := map[string]any{}
+	for key, value := range answer {
+		path, ok := f.paths[key]
+		if !ok {
+			path = []string{key}
+		}
+		object := restored
+		for _, name := range path[:len(path)-1] {
+			child, ok := object[name].(map[string]any)
+			if !ok {
+				child = map[string]any{}
+				object[name] = child
+			}
+			object = child
+		}
+		object[path[len(path)-1]] = value
+	}
+	return restored
+}
+
+// splitSchema splits a flat object schema into schemas of at most max
+// properties each.
+func splitSchema(schema map[string]any, max int) []map[string]any {
+	properties, _ := schema["properties"].(map[string]any)
+	if max <= 0 || len(properties) <= max {
+		return []map[string]any{schema}
+	}
+
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	required := map[string]bool{}
+	for _, name := range toStrings(schema["required"]) {
+		required[name] = true
+	}
+
+	var parts []map[string]any
+	for start := 0; start < len(names); start += max {
+		end := min(start+max, len(names))
+		partProperties := map[string]any{}
+		partRequired := []string{}
+		for _, name := range names[start:end] {
+			partProperties[name] = properties[name]
+			if required[name] {
+				partRequired = append(partRequired, name)
+			}
+		}
+		parts = append(parts, map[string]any{
+			"type":       "object",
+			"properties": partProperties,
+			"required":   partRequired,
+		})
+	}
+	return parts
+}
@@ -0,0 +1,206 @@
+package structured
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ollama/ollama/api"
+)
+
+// Strategy is a way to get a JSON answer matching schema from a model.
+//
+// Chat and Extract pick the strategy registered in Client.Strategies for
+// the schema title, then Client.Strategy, then Direct.
+type Strategy interface {
+	// Name identifies the strategy, as in StrategyByName.
+	Name() string
+	Generate(ctx context.Context, c *Client, model string, messages []api.Message, schema any) (string, error)
+}
+
+// StrategyByName returns the strategy called name ("direct", "prompted",
+// "two-pass", "tool-fill" or "per-field"), for instance to select it from
+// a flag.
+func StrategyByName(name string) (Strategy, error) {
+	switch name {
+	case "", "direct":
+		return Direct{}, nil
+	case "prompted":
+		return Prompted{}, nil
+	case "two-pass":
+		return TwoPass{}, nil
+	case "tool-fill":
+		return ToolFill{}, nil
+	case "per-field":
+		return PerField{}, nil
+	default:
+		return nil, fmt.Errorf("structured: unknown strategy %q", name)
+	}
+}
+
+// Direct asks for the JSON answer in one request, with the schema as the
+// format: Ollama turns it into a grammar that constrains the generation.
+type Direct struct{}
+
+func (Direct) Name() string { return "direct" }
+
+func (Direct) Generate(ctx context.Context, c *Client, model string, messages []api.Message, schema any) (string, error) {
+	return c.JSONChat(ctx, model, messages, schema)
+}
+
+// Prompted is the "old method": the schema is described in a system message
+// and the model is only constrained to answer with JSON. It works with
+// servers that don't support structured outputs.
+type Prompted struct{}
+
+// PromptedInstructions is the system message of the Prompted strategy,
+// followed by the JSON schema.
+var PromptedInstructions = "Output the results in JSON format, following this JSON schema:"
+
+func (Prompted) Name() string { return "prompted" }
+
+func (Prompted) Generate(ctx context.Context, c *Client, model string, messages []api.Message, schema any) (string, error) {
+	jsonModel, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	prompt := append([]api.Message{
+		{Role: "system", Content: PromptedInstructions + "\n" + string(jsonModel)},
+	}, messages...)
+	return c.JSONChat(ctx, model, prompt, nil)
+}
+
+// TwoPass lets the model answer freely first, then asks it, in a second
+// constrained request, to convert that answer to JSON. Small (1B) models
+// are often more accurate this way than with direct structured generation.
+type TwoPass struct {
+	// FormatModel runs the second pass when set, otherwise the same model
+	// is used for both passes.
+	FormatModel string
+}
+
+// TwoPassInstructions is the system message of the formatting pass.
+var TwoPassInstructions = `You convert text to JSON.
+Use only the information of the text given by the user, do not add anything.`
+
+func (TwoPass) Name() string { return "two-pass" }
+
+func (s TwoPass) Generate(ctx context.Context, c *Client, model string, messages []api.Message, schema any) (string, error) {
+	answer, err := c.TextChat(ctx, model, messages)
+	if err != nil {
+		return "", fmt.Errorf("structured: free-text pass: %w", err)
+	}
+
+	formatModel := s.FormatModel
+	if formatModel == "" {
+		formatModel = model
+	}
+	return c.JSONChat(ctx, formatModel, []api.Message{
+		{Role: "system", Content: TwoPassInstructions},
+		{Role: "user", Content: answer},
+	}, schema)
+}
+
+// ToolFill exposes the schema as the parameters of a tool and returns the
+// arguments of the tool call made by the model. It needs a model with tool
+// support, and the tool parameters of the Ollama API only describe flat
+// objects (array items and nested objects are not sent).
+type ToolFill struct{}
+
+// ToolFillName is the name of the tool the model is asked to call.
+var ToolFillName = "save_result"
+
+func (ToolFill) Name() string { return "tool-fill" }
+
+func (ToolFill) Generate(ctx context.Context, c *Client, model string, messages []api.Message, schema any) (string, error) {
+	tool := api.Tool{Type: "function"}
+	tool.Function.Name = ToolFillName
+	tool.Function.Description = "Save the requested information"
+
+	jsonModel, err := json.Marshal(schema)
+	if err != nil {
+		return "", err
+	}
+	if err := json.Unmarshal(jsonModel, &tool.Function.Parameters); err != nil {
+		return "", fmt.Errorf("structured: schema cannot be used as tool parameters: %w", err)
+	}
+
+	resp, err := c.chat(ctx, &api.ChatRequest{
+		Model:    model,
+		Messages: messages,
+		Tools:    api.Tools{tool},
+	})
+	if err != nil {
+		return "", err
+	}
+	for _, call := range resp.Message.ToolCalls {
+		if call.Function.Name == ToolFillName {
+			arguments, err := json.Marshal(call.Function.Arguments)
+			if err != nil {
+				return "", err
+			}
+			return string(arguments), nil
+		}
+	}
+	return "", fmt.Errorf("structured: the model did not call the %s tool", ToolFillName)
+}
+
+// strategyFor returns the strategy to use for schema, and whether it was
+// chosen explicitly (per schema or for the client). The default Direct
+// strategy falls back to Prompted when the server doesn't support JSON
+// schemas (generate does the same for the other strategies).
+func (c *Client) strategyFor(ctx context.Context, schema any) (Strategy, bool, error) {
+	if s, ok := c.Strategies[schemaTitle(schema)]; ok {
+		return s, true, nil
+	}
+	if c.Strategy != nil {
+		return c.Strategy, true, nil
+	}
+	if schema != nil {
+		supported, err := c.SupportsSchemas(ctx)
+		if err != nil {
+			return nil, false, err
+		}
+		if !supported {
+			return Prompted{}, false, nil
+		}
+	}
+	return Direct{}, false, nil
+}
+
+// generate runs the strategy selected for schema, adapted to the model
+// capabilities, and returns its answer with the strategy used.
+func (c *Client) generate(ctx context.Context, model string, messages []api.Message, schema any) (string, Strategy, error) {
+	strategy, explicit, err := c.strategyFor(ctx, schema)
+	if err != nil {
+		return "", nil, err
+	}
+	strategy, messages, err = c.adapt(model, schema, strategy, explicit, messages)
+	if err != nil {
+		return "", nil, err
+	}
+	if schema != nil && enforcementOf(strategy) == EnforceSchema {
+		// an explicit or adapted strategy constraining by the schema can't
+		// on an older server: the schema is given in the prompt instead
+		supported, err := c.SupportsSchemas(ctx)
+		if err != nil {
+			return "", nil, err
+		}
+		if !supported {
+			c.log().InfoContext(ctx, "server without JSON schema support, falling back",
+				"strategy", strategy.Name(), "fallback", Prompted{}.Name())
+			strategy = Prompted{}
+		}
+	}
+	raw, err := strategy.Generate(ctx, c, model, messages, schema)
+	return raw, strategy, err
+}
+
+// schemaTitle returns the title of a map schema, or "".
+func schemaTitle(schema any) string {
+	if m, ok := schema.(map[string]any); ok {
+		title, _ := m["title"].(string)
+		return title
+	}
+	return ""
+}
@@ -0,0 +1,217 @@
+package structured
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"01-json-output/internal/mockollama"
+
+	"github.com/ollama/ollama/api"
+)
+
+func TestTwoPass(t *testing.T) {
+	client, server := newTestClient(t,
+		mockollama.Response{Content: "The chicken is called Gallus gallus."},
+		mockollama.Response{Content: `{"name":"Gallus gallus"}`},
+	)
+	client.Strategy = TwoPass{}
+
+	result, err := client.Chat(context.Background(), "granite3-moe:1b", userMessages("chicken"), nameSchema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Raw != `{"name":"Gallus gallus"}` || result.Strategy != "two-pass" {
+		t.Errorf("result = %+v", result)
+	}
+
+	requests := server.Requests()
+	if len(requests[0].Format) != 0 {
+		t.Errorf("free-text pass format = %s", requests[0].Format)
+	}
+	if len(requests[1].Format) == 0 || requests[1].Messages[1].Content != "The chicken is called Gallus gallus." {
+		t.Errorf("format pass = %+v", requests[1])
+	}
+}
+
+func TestPromptedFallbackForOldServers(t *testing.T) {
+	client, server := newTestClient(t, mockollama.Response{Content: `{"name":"chicken"}`})
+	server.SetVersion("0.4.7")
+
+	result, err := client.Chat(context.Background(), "granite3-moe:1b", userMessages("chicken"), nameSchema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Strategy != "prompted" || result.Enforcement != EnforceJSON {
+		t.Errorf("strategy = %s, enforcement = %s", result.Strategy, result.Enforcement)
+	}
+	req := server.Requests()[0]
+	if string(req.Format) != `"json"` || !strings.Contains(req.Messages[0].Content, `"required"`) {
+		t.Errorf("request = %+v", req)
+	}
+}
+
+func TestExplicitStrategyOnOldServers(t *testing.T) {
+	for _, strategy := range []Strategy{Direct{}, TwoPass{}, PerField{}} {
+		client, server := newTestClient(t, mockollama.Response{Content: `{"name":"chicken"}`})
+		server.SetVersion("0.4.7")
+		client.Strategy = strategy
+
+		result, err := client.Chat(context.Background(), "granite3-moe:1b", userMessages("chicken"), nameSchema())
+		if err != nil {
+			t.Fatalf("%s: %v", strategy.Name(), err)
+		}
+		if result.Strategy != "prompted" || result.Enforcement != EnforceJSON {
+			t.Errorf("%s: strategy = %s, enforcement = %s", strategy.Name(), result.Strategy, result.Enforcement)
+		}
+		if format := string(server.Requests()[0].Format); format != `"json"` {
+			t.Errorf("%s: format = %s", strategy.Name(), format)
+		}
+	}
+}
+
+func TestToolFill(t *testing.T) {
+	client, server := newTestClient(t, mockollama.Response{
+		ToolCalls: []api.ToolCall{{Function: api.ToolCallFunction{
+			Name:      ToolFillName,
+			Arguments: api.ToolCallFunctionArguments{"name": "chicken"},
+		}}},
+	})
+	client.Strategy = ToolFill{}
+
+	result, err := client.Chat(context.Background(), "granite3-moe:1b", userMessages("chicken"), nameSchema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Raw != `{"name":"chicken"}` || result.Enforcement != EnforceTool {
+		t.Errorf("result = %+v", result)
+	}
+	tools := server.Requests()[0].Tools
+	if len(tools) != 1 || tools[0].Function.Parameters.Required[0] != "name" {
+		t.Errorf("tools = %+v", tools)
+	}
+}
+
+func TestPerField(t *testing.T) {
+	client, server := newTestClient(t,
+		mockollama.Response{Content: `{"average_length":1.5}`, EvalCount: 5},
+		mockollama.Response{Content: `{"countries":["China"]}`, EvalCount: 7},
+		mockollama.Response{Content: `{"scientific_name":"Gallus gallus"}`, EvalCount: 9},
+	)
+	var costs []FieldCost
+	client.Strategy = PerField{OnField: func(c FieldCost) { costs = append(costs, c) }}
+
+	animal, err := Extract[testAnimal](context.Background(), client, "granite3-moe:1b", "chicken")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if animal.ScientificName != "Gallus gallus" || animal.AverageLength != 1.5 || animal.Countries[0] != "China" {
+		t.Errorf("animal = %+v", animal)
+	}
+	if len(costs) != 3 || costs[2].Field != "scientific_name" || costs[2].OutputTokens != 9 {
+		t.Errorf("costs = %+v", costs)
+	}
+	if n := len(server.Requests()); n != 3 {
+		t.Errorf("%d requests, want one per field", n)
+	}
+}
+
+func TestStrategyPerSchema(t *testing.T) {
+	client, _ := newTestClient(t, mockollama.Response{Content: `{"scientific_name":"x","average_length":1,"countries":[]}`})
+	client.Strategies = map[string]Strategy{"testAnimal": Prompted{}}
+
+	result, err := client.Chat(context.Background(), "granite3-moe:1b", userMessages("chicken"), mustSchema[testAnimal](t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Strategy != "prompted" {
+		t.Errorf("strategy = %s", result.Strategy)
+	}
+}
+
+func TestStrategyByName(t *testing.T) {
+	for _, name := range []string{"direct", "prompted", "two-pass", "tool-fill", "per-field"} {
+		s, err := StrategyByName(name)
+		if err != nil || s.Name() != name {
+			t.Errorf("StrategyByName(%q) = %v, %v", name, s, err)
+		}
+	}
+	if _, err := StrategyByName("magic"); err == nil {
+		t.Error("expected an error for an unknown strategy")
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	client, server := newTestClient(t, mockollama.Response{Content: `{"name":"chicken"}`})
+	client.Capabilities = Capabilities{"deepseek-r1": {JSONHint: true, Strategy: "prompted"}}
+
+	result, err := client.Chat(context.Background(), "deepseek-r1:1.5b", userMessages("chicken"), nameSchema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Strategy != "prompted" {
+		t.Errorf("strategy = %s", result.Strategy)
+	}
+	hinted := false
+	for _, m := range server.Requests()[0].Messages {
+		hinted = hinted || m.Content == JSONHintPrompt
+	}
+	if !hinted {
+		t.Errorf("messages = %+v, want the JSON hint", server.Requests()[0].Messages)
+	}
+}
+
+func TestSimplified(t *testing.T) {
+	type Size struct {
+		Length float64 `json:"length"`
+		Weight float64 `json:"weight"`
+	}
+	type Animal struct {
+		Name string `json:"name" jsonschema:"enum=chicken|duck"`
+		Size Size   `json:"size"`
+	}
+	client, server := newTestClient(t,
+		mockollama.Response{Content: `{"name":"chicken","size.length":1.5}`},
+		mockollama.Response{Content: `{"size.weight":5}`},
+	)
+	client.Capabilities = Capabilities{"tiny": {Simplify: true, MaxProperties: 2}}
+
+	animal, err := Extract[Animal](context.Background(), client, "tiny", "chicken")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if animal.Name != "chicken" || animal.Size.Length != 1.5 || animal.Size.Weight != 5 {
+		t.Errorf("animal = %+v", animal)
+	}
+	if first := string(server.Requests()[0].Format); !strings.Contains(first, "One of: chicken, duck.") {
+		t.Errorf("enum not simplified: %s", first)
+	}
+}
+
+func TestSimplifiedIntegerEnum(t *testing.T) {
+	type Animal struct {
+		Legs int `json:"legs" jsonschema:"enum=2|4"`
+	}
+	client, server := newTestClient(t, mockollama.Response{Content: `{"legs":4}`})
+	client.Capabilities = Capabilities{"tiny": {Simplify: true}}
+
+	animal, err := Extract[Animal](context.Background(), client, "tiny", "chicken")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if animal.Legs != 4 {
+		t.Errorf("animal = %+v", animal)
+	}
+	if first := string(server.Requests()[0].Format); !strings.Contains(first, `"enum":[2,4]`) {
+		t.Errorf("integer enum simplified: %s", first)
+	}
+}
+
+func mustSchema[T any](t *testing.T) map[string]any {
+	t.Helper()
+	schema, err := SchemaOf[T]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return schema
+}
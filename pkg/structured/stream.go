@@ -0,0 +1,169 @@
+package structured
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+)
+
+// FieldFunc is called by StreamJSONChat each time a top-level field of the
+// answer is complete.
+type FieldFunc func(name string, value json.RawMessage)
+
+// StreamJSONChat is like JSONChat but streams the answer: onField is called
+// as soon as each top-level field is complete, so a UI can render
+// scientific_name before countries has finished generating.
+//
+// The complete answer is validated against schema at the end (without
+// retry), a *ValidationError is returned if it doesn't match.
+func (c *Client) StreamJSONChat(ctx context.Context, model string, messages []api.Message, schema any, onField FieldFunc) (string, error) {
+	format := json.RawMessage(`"json"`)
+	if schema != nil {
+		jsonModel, err := json.Marshal(schema)
+		if err != nil {
+			return "", err
+		}
+		format = json.RawMessage(jsonModel)
+	}
+
+	stream := true
+	req := &api.ChatRequest{
+		Model:    model,
+		Messages: messages,
+		Options:  c.Options.ToMap(),
+		Stream:   &stream,
+		Format:   format,
+	}
+
+	if err := waitRateLimiter(ctx); err != nil {
+		return "", err
+	}
+	c.logRequest(ctx, req)
+
+	parser := &fieldParser{onField: onField}
+	var final api.ChatResponse
+	err := c.api.Chat(ctx, req, func(resp api.ChatResponse) error {
+		parser.Write(resp.Message.Content)
+		final = resp
+		return nil
+	})
+	if err != nil {
+		c.log().WarnContext(ctx, "chat request failed", "model", model, "error", err)
+		return "", err
+	}
+	c.logResponse(ctx, final, parser.String())
+
+	answer := c.clean(parser.String())
+	if final.DoneReason == doneLength {
+		return "", &ValidationError{Raw: answer, Attempts: 1, Violations: []Violation{truncatedViolation}}
+	}
+	if schema != nil {
+		violations, err := ValidateJSON(schema, answer)
+		if err != nil {
+			return "", err
+		}
+		if len(violations) > 0 {
+			return "", &ValidationError{Raw: answer, Attempts: 1, Violations: violations}
+		}
+	}
+	return answer, nil
+}
+
+// fieldParser incrementally scans a JSON object and reports its top-level
+// fields as soon as their value is complete.
+type fieldParser struct {
+	onField FieldFunc
+
+	buf   strings.Builder
+	pos   int // next byte to scan
+	depth int
+
+	inString bool
+	escaped  bool
+
+	key        string
+	readingKey bool
+	keyStart   int
+	valueStart int // -1 when no top-level value is being read
+	literal    bool
+}
+
+// Write adds a chunk of the answer and reports the completed fields.
+func (p *fieldParser) Write(chunk string) {
+	if p.buf.Len() == 0 {
+		p.valueStart = -1
+	}
+	p.buf.WriteString(chunk)
+	data := p.buf.String()
+
+	for ; p.pos < len(data); p.pos++ {
+		ch := data[p.pos]
+
+		if p.inString {
+			switch {
+			case p.escaped:
+				p.escaped = false
+			case ch == '\\':
+				p.escaped = true
+			case ch == '"':
+				p.inString = false
+				if p.depth == 1 && p.readingKey {
+					p.key = data[p.keyStart+1 : p.pos]
+					p.readingKey = false
+				} else if p.depth == 1 && p.valueStart >= 0 {
+					p.emit(data, p.pos+1)
+				}
+			}
+			continue
+		}
+
+		// end of a number, true, false or null at the top level
+		if p.literal && (ch == ',' || ch == '}' || isSpace(ch)) {
+			p.emit(data, p.pos)
+		}
+
+		switch {
+		case ch == '"':
+			p.inString = true
+			if p.depth == 1 && p.key == "" && p.valueStart < 0 {
+				p.readingKey = true
+				p.keyStart = p.pos
+			} else if p.depth == 1 && p.valueStart < 0 {
+				p.valueStart = p.pos
+			}
+		case ch == '{' || ch == '[':
+			if p.depth == 1 && p.key != "" && p.valueStart < 0 {
+				p.valueStart = p.pos
+			}
+			p.depth++
+		case ch == '}' || ch == ']':
+			p.depth--
+			if p.depth == 1 && p.valueStart >= 0 {
+				p.emit(data, p.pos+1)
+			}
+		case p.depth == 1 && p.key != "" && p.valueStart < 0 && ch != ':' && !isSpace(ch):
+			p.valueStart = p.pos
+			p.literal = true
+		}
+	}
+}
+
+func (p *fieldParser) emit(data string, end int) {
+	if p.onField != nil {
+		p.onField(p.key, json.RawMessage(data[p.valueStart:end]))
+	}
+	p.key = ""
+	p.valueStart = -1
+	p.literal = false
+}
+
+// String returns the whole answer received so far.
+func (p *fieldParser) String() string {
+	return p.buf.String()
+}
+
+func isSpace(ch byte) bool {
+	return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r'
+}
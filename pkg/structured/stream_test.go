@@ -0,0 +1,74 @@
+package structured
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"01-json-output/internal/mockollama"
+)
+
+func TestStreamJSONChat(t *testing.T) {
+	client, _ := newTestClient(t, mockollama.Response{
+		Chunks: []string{`{"scientific_name": "Gallus`, ` gallus", "average_length"`, `: 1.5, "countries": ["China", `, `"Iran"]}`},
+	})
+
+	type field struct{ name, value string }
+	var fields []field
+	answer, err := client.StreamJSONChat(context.Background(), "granite3-moe:1b", userMessages("chicken"), mustSchema[testAnimal](t),
+		func(name string, value json.RawMessage) {
+			fields = append(fields, field{name, string(value)})
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if answer != `{"scientific_name": "Gallus gallus", "average_length": 1.5, "countries": ["China", "Iran"]}` {
+		t.Errorf("answer = %s", answer)
+	}
+	want := []field{
+		{"scientific_name", `"Gallus gallus"`},
+		{"average_length", "1.5"},
+		{"countries", `["China", "Iran"]`},
+	}
+	if len(fields) != len(want) {
+		t.Fatalf("fields = %v", fields)
+	}
+	for i := range want {
+		if fields[i] != want[i] {
+			t.Errorf("field %d = %v, want %v", i, fields[i], want[i])
+		}
+	}
+}
+
+func TestFieldParserTricks(t *testing.T) {
+	doc := `{"a": "x\"}y", "n" : -1.5e3,"b":true, "o": {"k": [1, {"z": "]"}]}, "last": null}`
+	want := map[string]string{"a": `"x\"}y"`, "n": "-1.5e3", "b": "true", "o": `{"k": [1, {"z": "]"}]}`, "last": "null"}
+
+	// whatever the chunk size, the same fields are reported
+	for _, size := range []int{1, 2, 7, len(doc)} {
+		got := map[string]string{}
+		p := &fieldParser{onField: func(name string, value json.RawMessage) { got[name] = string(value) }}
+		for i := 0; i < len(doc); i += size {
+			p.Write(doc[i:min(i+size, len(doc))])
+		}
+		if len(got) != len(want) {
+			t.Errorf("size %d: fields = %v", size, got)
+		}
+		for name, value := range want {
+			if got[name] != value {
+				t.Errorf("size %d: %s = %s, want %s", size, name, got[name], value)
+			}
+		}
+	}
+}
+
+func TestStreamJSONChatValidation(t *testing.T) {
+	client, _ := newTestClient(t, mockollama.Response{Content: `{"scientific_name": "Gallus gallus"}`})
+
+	_, err := client.StreamJSONChat(context.Background(), "granite3-moe:1b", userMessages("chicken"), mustSchema[testAnimal](t), nil)
+	var invalid *ValidationError
+	if !errors.As(err, &invalid) || len(invalid.Violations) != 2 {
+		t.Errorf("err = %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+package structured
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+// Metrics are the costs of a structured chat, summed over its requests (a
+// structured chat can make several: retries, strategies, fallbacks...).
+type Metrics struct {
+	Requests     int
+	PromptTokens int
+	OutputTokens int
+
+	// Durations measured by the server.
+	LoadDuration       time.Duration
+	PromptEvalDuration time.Duration
+	EvalDuration       time.Duration
+	TotalDuration      time.Duration
+
+	// Truncated counts the answers cut by the output token limit
+	// (done_reason "length"), which Chat and Extract retry.
+	Truncated int
+
+	// WallTime is the duration of the call, as seen by the client.
+	WallTime time.Duration
+	// Cached is set when the result comes from Client.Cache, no request
+	// being made.
+	Cached bool
+}
+
+// TokensPerSecond is the generation rate of the model (eval rate).
+func (m Metrics) TokensPerSecond() float64 {
+	if m.EvalDuration <= 0 {
+		return 0
+	}
+	return float64(m.OutputTokens) / m.EvalDuration.Seconds()
+}
+
+// PromptTokensPerSecond is the prompt evaluation rate of the model.
+func (m Metrics) PromptTokensPerSecond() float64 {
+	if m.PromptEvalDuration <= 0 {
+		return 0
+	}
+	return float64(m.PromptTokens) / m.PromptEvalDuration.Seconds()
+}
+
+func (m *Metrics) add(other Metrics) {
+	m.Requests += other.Requests
+	m.PromptTokens += other.PromptTokens
+	m.OutputTokens += other.OutputTokens
+	m.LoadDuration += other.LoadDuration
+	m.PromptEvalDuration += other.PromptEvalDuration
+	m.EvalDuration += other.EvalDuration
+	m.TotalDuration += other.TotalDuration
+	m.Truncated += other.Truncated
+}
+
+// usage counts the chat requests made with a context, see withUsage.
+// Usages are nested: a request counts in the usage of its context and in
+// the ones of the parent contexts.
+type usage struct {
+	mu      sync.Mutex
+	metrics Metrics
+	parent  *usage
+}
+
+type usageKey struct{}
+
+// withUsage returns a context in which the chat requests are counted in
+// the returned usage.
+func withUsage(ctx context.Context) (context.Context, *usage) {
+	parent, _ := ctx.Value(usageKey{}).(*usage)
+	u := &usage{parent: parent}
+	return context.WithValue(ctx, usageKey{}, u), u
+}
+
+// Metrics returns the metrics counted so far.
+func (u *usage) Metrics() Metrics {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.metrics
+}
+
+// doneLength is the done_reason of an answer cut by the output token limit.
+const doneLength = "length"
+
+// recordUsage adds the metrics of resp to the usages of ctx, if any.
+func recordUsage(ctx context.Context, resp api.ChatResponse) {
+	truncated := 0
+	if resp.DoneReason == doneLength {
+		truncated = 1
+	}
+	u, _ := ctx.Value(usageKey{}).(*usage)
+	for ; u != nil; u = u.parent {
+		u.mu.Lock()
+		u.metrics.add(Metrics{
+			Requests:           1,
+			Truncated:          truncated,
+			PromptTokens:       resp.PromptEvalCount,
+			OutputTokens:       resp.EvalCount,
+			LoadDuration:       resp.LoadDuration,
+			PromptEvalDuration: resp.PromptEvalDuration,
+			EvalDuration:       resp.EvalDuration,
+			TotalDuration:      resp.TotalDuration,
+		})
+		u.mu.Unlock()
+	}
+}
@@ -0,0 +1,281 @@
+package structured
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Violation is one reason a JSON document does not match its schema.
+type Violation struct {
+	// Path of the value in the document, like "countries[2]", empty for
+	// the document itself.
+	Path    string
+	Message string
+}
+
+func (v Violation) String() string {
+	if v.Path == "" {
+		return v.Message
+	}
+	return v.Path + ": " + v.Message
+}
+
+// ValidationError is returned when the model answer still doesn't match
+// the schema after all the retries.
+type ValidationError struct {
+	// Raw is the last answer of the model.
+	Raw        string
+	Attempts   int
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		messages[i] = v.String()
+	}
+	return fmt.Sprintf("structured: invalid answer after %d attempt(s): %s", e.Attempts, strings.Join(messages, "; "))
+}
+
+// ValidateJSON checks the JSON document raw against schema, see Validate.
+func ValidateJSON(schema any, raw string) ([]Violation, error) {
+	var data any
+	if err := json.Unmarshal([]byte(raw), &data); err != nil {
+		return []Violation{{Message: "invalid JSON: " + err.Error()}}, nil
+	}
+	return Validate(schema, data)
+}
+
+// Validate checks data, as decoded by encoding/json, against schema and
+// returns the violations found. The error is only set when the schema
+// itself cannot be read.
+//
+// The supported keywords are type, properties, required, items, enum,
+// minimum, maximum, minLength, maxLength, pattern and format (date-time,
+// date and time); the other ones are ignored.
+func Validate(schema any, data any) ([]Violation, error) {
+	s, err := schemaMap(schema)
+	if err != nil {
+		return nil, err
+	}
+	var violations []Violation
+	validateValue(s, data, "", &violations)
+	return violations, nil
+}
+
+// schemaMap returns schema as a map, converting it through JSON if needed.
+func schemaMap(schema any) (map[string]any, error) {
+	if m, ok := schema.(map[string]any); ok {
+		return m, nil
+	}
+	jsonModel, err := json.Marshal(schema)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]any
+	if err := json.Unmarshal(jsonModel, &m); err != nil {
+		return nil, fmt.Errorf("structured: schema is not a JSON object: %w", err)
+	}
+	return m, nil
+}
+
+func validateValue(schema map[string]any, value any, path string, violations *[]Violation) {
+	report := func(format string, args ...any) {
+		*violations = append(*violations, Violation{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if types := schemaTypes(schema["type"]); len(types) > 0 {
+		matched := false
+		for _, t := range types {
+			if hasType(value, t) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			report("expected %s, got %s", strings.Join(types, " or "), jsonType(value))
+			return
+		}
+	}
+
+	if enum, ok := schema["enum"]; ok && !inEnum(value, enum) {
+		report("%s is not one of %s", jsonString(value), jsonString(enum))
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		validateObject(schema, v, path, violations)
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				validateValue(items, item, fmt.Sprintf("%s[%d]", path, i), violations)
+			}
+		}
+	case float64:
+		if minimum, ok := toFloat(schema["minimum"]); ok && v < minimum {
+			report("%v is lower than the minimum %v", v, minimum)
+		}
+		if maximum, ok := toFloat(schema["maximum"]); ok && v > maximum {
+			report("%v is greater than the maximum %v", v, maximum)
+		}
+	case string:
+		length := utf8.RuneCountInString(v)
+		if minLength, ok := toFloat(schema["minLength"]); ok && float64(length) < minLength {
+			report("%q is shorter than %v characters", v, minLength)
+		}
+		if maxLength, ok := toFloat(schema["maxLength"]); ok && float64(length) > maxLength {
+			report("%q is longer than %v characters", v, maxLength)
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(v) {
+				report("%q does not match the pattern %s", v, pattern)
+			}
+		}
+		if format, ok := schema["format"].(string); ok {
+			if layout, known := formatLayouts[format]; known {
+				if _, err := time.Parse(layout, v); err != nil {
+					report("%q is not a valid %s", v, format)
+				}
+			}
+		}
+	}
+}
+
+// formatLayouts are the checked string formats, the other ones are ignored.
+var formatLayouts = map[string]string{
+	"date-time": time.RFC3339,
+	"date":      time.DateOnly,
+	"time":      "15:04:05Z07:00",
+}
+
+// inEnum tells whether value is one of the values of enum, compared as JSON.
+func inEnum(value any, enum any) bool {
+	jsonValue := jsonString(value)
+	switch values := enum.(type) {
+	case []any:
+		for _, v := range values {
+			if jsonString(v) == jsonValue {
+				return true
+			}
+		}
+	case []string:
+		for _, v := range values {
+			if jsonString(v) == jsonValue {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func jsonString(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+func validateObject(schema map[string]any, object map[string]any, path string, violations *[]Violation) {
+	for _, name := range toStrings(schema["required"]) {
+		if _, ok := object[name]; !ok {
+			*violations = append(*violations, Violation{Path: joinPath(path, name), Message: "missing required field"})
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]any)
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if property, ok := properties[name].(map[string]any); ok {
+			validateValue(property, object[name], joinPath(path, name), violations)
+		}
+	}
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func schemaTypes(t any) []string {
+	switch t := t.(type) {
+	case string:
+		return []string{t}
+	default:
+		return toStrings(t)
+	}
+}
+
+func hasType(value any, t string) bool {
+	switch t {
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == math.Trunc(f)
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	default:
+		return jsonType(value) == t
+	}
+}
+
+// jsonType returns the JSON type name of a decoded value.
+func jsonType(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	default:
+		return 0, false
+	}
+}
+
+// toStrings reads a []string or a decoded []any of strings.
+func toStrings(v any) []string {
+	switch s := v.(type) {
+	case []string:
+		return s
+	case []any:
+		strs := make([]string, 0, len(s))
+		for _, item := range s {
+			if str, ok := item.(string); ok {
+				strs = append(strs, str)
+			}
+		}
+		return strs
+	default:
+		return nil
+	}
+}
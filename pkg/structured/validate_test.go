@@ -0,0 +1,58 @@
+package structured
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateJSON(t *testing.T) {
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"name":  map[string]any{"type": "string", "minLength": 2, "pattern": "^[A-Z]"},
+			"legs":  map[string]any{"type": "integer", "minimum": 0, "maximum": 8},
+			"diet":  map[string]any{"type": "string", "enum": []string{"herbivore", "carnivore"}},
+			"born":  map[string]any{"type": "string", "format": "date"},
+			"tags":  map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+			"owner": map[string]any{"type": "object", "properties": map[string]any{"name": map[string]any{"type": "string"}}, "required": []string{"name"}},
+		},
+		"required": []string{"name", "legs"},
+	}
+
+	tests := []struct {
+		name string
+		raw  string
+		want []Violation
+	}{
+		{"valid", `{"name":"Chicken","legs":2,"diet":"herbivore","born":"2024-12-06","tags":["bird"],"owner":{"name":"Bob"}}`, nil},
+		{"missing", `{"name":"Chicken"}`, []Violation{{"legs", "missing required field"}}},
+		{"wrong type", `{"name":"Chicken","legs":"two"}`, []Violation{{"legs", "expected integer, got string"}}},
+		{"not an integer", `{"name":"Chicken","legs":2.5}`, []Violation{{"legs", "expected integer, got number"}}},
+		{"bounds", `{"name":"Chicken","legs":10}`, []Violation{{"legs", "10 is greater than the maximum 8"}}},
+		{"enum", `{"name":"Chicken","legs":2,"diet":"seeds"}`, []Violation{{"diet", `"seeds" is not one of ["herbivore","carnivore"]`}}},
+		{"pattern and length", `{"name":"c","legs":2}`, []Violation{{"name", `"c" is shorter than 2 characters`}, {"name", `"c" does not match the pattern ^[A-Z]`}}},
+		{"format", `{"name":"Chicken","legs":2,"born":"yesterday"}`, []Violation{{"born", `"yesterday" is not a valid date`}}},
+		{"items", `{"name":"Chicken","legs":2,"tags":["bird",1]}`, []Violation{{"tags[1]", "expected string, got number"}}},
+		{"nested", `{"name":"Chicken","legs":2,"owner":{}}`, []Violation{{"owner.name", "missing required field"}}},
+		{"not an object", `[]`, []Violation{{"", "expected object, got array"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ValidateJSON(schema, tt.raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("violations = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidationErrorMessage(t *testing.T) {
+	err := &ValidationError{Attempts: 2, Violations: []Violation{{"legs", "missing required field"}, {"", "expected object, got array"}}}
+	want := "structured: invalid answer after 2 attempt(s): legs: missing required field; expected object, got array"
+	if err.Error() != want {
+		t.Errorf("error = %q", err.Error())
+	}
+}
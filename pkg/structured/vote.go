@@ -0,0 +1,184 @@
+package structured
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/ollama/ollama/api"
+)
+
+// DefaultVotingTemperature is the temperature of the samples when
+// Voting.Temperature is not set: at temperature 0 they would all be the
+// same.
+const DefaultVotingTemperature = 0.7
+
+// Voting asks the model several times and merges the valid answers by
+// majority vote per top-level field (self-consistency). Result.Confidence
+// holds the share of the answers agreeing with each merged value.
+type Voting struct {
+	// Samples is the number of answers asked for.
+	Samples int
+	// Temperature of the samples, DefaultVotingTemperature when 0.
+	Temperature float64
+}
+
+// vote gets the samples of the voting, with the settings of c otherwise,
+// and merges them. The samples not matching the schema don't vote; the
+// error of the last one is returned when none does.
+func (c *Client) vote(ctx context.Context, model string, messages []api.Message, schema any) (*Result, error) {
+	sampler := c.clone()
+	sampler.Voting = nil
+	temperature := c.Voting.Temperature
+	if temperature == 0 {
+		temperature = DefaultVotingTemperature
+	}
+	sampler.Options.Temperature = Float(temperature)
+	// a fixed seed would give the same sample every time
+	sampler.Options.Seed = nil
+
+	var samples []*Result
+	var err error
+	for i := 0; i < c.Voting.Samples; i++ {
+		var sample *Result
+		sample, err = sampler.generateFallback(ctx, model, messages, schema)
+		var invalid *ValidationError
+		if errors.As(err, &invalid) {
+			c.log().DebugContext(ctx, "invalid sample, not voting", "model", model, "sample", i+1)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, sample)
+	}
+	if len(samples) == 0 {
+		return nil, err
+	}
+
+	result := mergeVotes(samples)
+	if schema != nil {
+		violations, err := ValidateJSON(schema, result.Raw)
+		if err != nil {
+			return nil, err
+		}
+		if len(violations) > 0 {
+			// the merge of valid answers can break constraints between
+			// fields, the sample the most in line with the votes is used
+			return closestSample(samples, result), nil
+		}
+	}
+	return result, nil
+}
+
+// ballot is a value and its votes.
+type ballot struct {
+	value json.RawMessage
+	votes int
+}
+
+// mergeVotes merges the samples by majority per top-level field, the value
+// of the earliest sample winning ties. An absent field is a vote for its
+// absence. Answers that are not all objects are voted as a whole, under the
+// field "".
+func mergeVotes(samples []*Result) *Result {
+	whole := false
+	objects := make([]map[string]json.RawMessage, len(samples))
+	fields := map[string]bool{}
+	for i, sample := range samples {
+		if err := json.Unmarshal([]byte(sample.Raw), &objects[i]); err != nil || objects[i] == nil {
+			objects = nil
+			break
+		}
+		for name := range objects[i] {
+			fields[name] = true
+		}
+	}
+	if objects == nil {
+		whole = true
+		objects = make([]map[string]json.RawMessage, len(samples))
+		for i, sample := range samples {
+			objects[i] = map[string]json.RawMessage{"": json.RawMessage(sample.Raw)}
+		}
+		fields = map[string]bool{"": true}
+	}
+
+	merged := map[string]json.RawMessage{}
+	confidence := map[string]float64{}
+	for _, name := range sortedKeys(fields) {
+		var ballots []*ballot
+		for _, object := range objects {
+			ballots = countVote(ballots, object[name])
+		}
+		winner := ballots[0]
+		for _, b := range ballots[1:] {
+			if b.votes > winner.votes {
+				winner = b
+			}
+		}
+		if winner.value != nil {
+			merged[name] = winner.value
+		}
+		confidence[name] = float64(winner.votes) / float64(len(samples))
+	}
+
+	result := *samples[0]
+	result.Confidence = confidence
+	if whole {
+		result.Raw = string(merged[""])
+		return &result
+	}
+	raw, _ := json.Marshal(merged)
+	result.Raw = string(raw)
+	return &result
+}
+
+// countVote adds a vote for value, values being compared once normalized
+// (key order and spaces don't matter).
+func countVote(ballots []*ballot, value json.RawMessage) []*ballot {
+	normalized := normalizeJSON(value)
+	for _, b := range ballots {
+		if string(normalizeJSON(b.value)) == string(normalized) {
+			b.votes++
+			return ballots
+		}
+	}
+	return append(ballots, &ballot{value: value, votes: 1})
+}
+
+func normalizeJSON(value json.RawMessage) json.RawMessage {
+	if value == nil {
+		return nil
+	}
+	var v any
+	if err := json.Unmarshal(value, &v); err != nil {
+		return value
+	}
+	normalized, _ := json.Marshal(v)
+	return normalized
+}
+
+// closestSample returns the sample sharing the most field values with the
+// merged result, with its confidence.
+func closestSample(samples []*Result, merged *Result) *Result {
+	var mergedObject map[string]json.RawMessage
+	json.Unmarshal([]byte(merged.Raw), &mergedObject)
+
+	best, bestScore := samples[0], -1
+	for _, sample := range samples {
+		var object map[string]json.RawMessage
+		json.Unmarshal([]byte(sample.Raw), &object)
+		score := 0
+		for name, value := range mergedObject {
+			if string(normalizeJSON(object[name])) == string(normalizeJSON(value)) {
+				score++
+			}
+		}
+		if score > bestScore {
+			best, bestScore = sample, score
+		}
+	}
+	result := *best
+	result.Confidence = merged.Confidence
+	return &result
+}
@@ -0,0 +1,57 @@
+package structured
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"01-json-output/internal/mockollama"
+)
+
+func TestVoting(t *testing.T) {
+	client, server := newTestClient(t,
+		mockollama.Response{Content: `{"name":"chicken","legs":2}`},
+		mockollama.Response{Content: `{"legs":4,"name":"chicken"}`},
+		mockollama.Response{Content: `{"legs":4}`},
+		mockollama.Response{Content: `{"name": "chicken", "legs": 2}`},
+	)
+	client.Voting = &Voting{Samples: 4}
+
+	result, err := client.Chat(context.Background(), "granite3-moe:1b", userMessages("chicken"), nameSchema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Raw != `{"legs":2,"name":"chicken"}` {
+		t.Errorf("raw = %s", result.Raw)
+	}
+	// the third sample doesn't match the schema and doesn't vote
+	want := map[string]float64{"legs": 2.0 / 3, "name": 1}
+	if !reflect.DeepEqual(result.Confidence, want) {
+		t.Errorf("confidence = %v, want %v", result.Confidence, want)
+	}
+
+	requests := server.Requests()
+	if len(requests) != 4 {
+		t.Fatalf("%d requests, want 4", len(requests))
+	}
+	if requests[0].Options["temperature"] != DefaultVotingTemperature {
+		t.Errorf("temperature = %v", requests[0].Options["temperature"])
+	}
+}
+
+func TestVotingAllInvalid(t *testing.T) {
+	client, _ := newTestClient(t, mockollama.Response{Content: `{}`})
+	client.Voting = &Voting{Samples: 2}
+
+	if _, err := client.Chat(context.Background(), "granite3-moe:1b", userMessages("chicken"), nameSchema()); err == nil {
+		t.Error("no error")
+	}
+}
+
+func TestMergeVotesWhole(t *testing.T) {
+	samples := []*Result{{Raw: `["a","b"]`}, {Raw: `["a"]`}, {Raw: `[ "a", "b" ]`}}
+	merged := mergeVotes(samples)
+	if merged.Raw != `["a","b"]` || merged.Confidence[""] != 2.0/3 {
+		t.Errorf("merged = %+v", merged)
+	}
+}